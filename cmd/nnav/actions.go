@@ -0,0 +1,493 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// isSavedSearch reports whether n is a saved search (a virtual folder),
+// which file operations do not apply to; the status says where to change it.
+func (m *model) isSavedSearch(n *Node) bool {
+	if n.Kind != kindSearch {
+		return false
+	}
+	m.status = "saved search: edit " + savedSearchPrefix + strings.TrimPrefix(n.Path, "search:") + " in ~/.nnav"
+	return true
+}
+
+// selected returns the node under the cursor, or nil when the tree is empty.
+func (m *model) selected() *Node {
+	if len(m.visible) == 0 {
+		return nil
+	}
+	return m.visible[m.cursor].N
+}
+
+// isLoaded reports whether dir's children have been read from disk, i.e.
+// whether in-place insertions keep it consistent with the filesystem.
+func (m *model) isLoaded(dir *Node) bool {
+	return dir == m.root || dir.Expanded || len(dir.Children) > 0
+}
+
+// newNote prompts for a file name (or, with naming=id, a title that becomes
+// "<id>-<slug>.md"), lets the user pick a template when the templates
+// directory has any, creates the note and opens it in the editor.
+func (m *model) newNote() {
+	dir := m.targetDir().Path
+	idMode := idNaming()
+	label := "new note in "
+	if idMode {
+		label = "new note title in "
+	}
+	m.ask(label+m.displayPath(dir)+": ", "", func(m *model, input string) tea.Cmd {
+		name, title := input, ""
+		if idMode {
+			title = strings.TrimSpace(input)
+			if title == "" {
+				m.status = "create failed: title must not be empty"
+				return nil
+			}
+			n, err := idFileName(title, time.Now())
+			if err != nil {
+				m.status = "create failed: " + err.Error()
+				return nil
+			}
+			name = n
+		}
+		tmpls, err := listTemplates()
+		if err != nil {
+			m.status = "templates error: " + err.Error()
+			return nil
+		}
+		if len(tmpls) == 0 {
+			return m.createAndEdit(dir, name, title, "")
+		}
+		items := append([]string{"(blank)"}, tmpls...)
+		m.choose("template for "+input, items, func(m *model, i int) tea.Cmd {
+			tmpl := ""
+			if i > 0 {
+				tmpl = items[i]
+			}
+			return m.createAndEdit(dir, name, title, tmpl)
+		})
+		return nil
+	})
+}
+
+// createAndEdit creates the note (optionally from a template), logs it for
+// undo and opens it in the editor; the cursor lands on it afterwards.
+func (m *model) createAndEdit(dir, name, title, tmpl string) tea.Cmd {
+	p, err := createNote(dir, name, title, tmpl)
+	if err != nil {
+		m.status = "create failed: " + err.Error()
+		return nil
+	}
+	m.record("create "+m.displayPath(p), fileOp{Kind: opCreate, To: p})
+	if cmd := m.openInEditor(p); cmd != nil {
+		return cmd
+	}
+	// Editor unavailable: still show the new note (status keeps the error).
+	_ = m.reload(p)
+	return nil
+}
+
+// newDir prompts for a name and creates a subdirectory in the target directory.
+// The new node is inserted into the loaded tree rather than rebuilding it.
+func (m *model) newDir() {
+	parent := m.targetDir()
+	m.ask("new directory in "+m.displayPath(parent.Path)+": ", "", func(m *model, name string) tea.Cmd {
+		p, err := makeDir(parent.Path, name)
+		if err != nil {
+			m.status = "mkdir failed: " + err.Error()
+			return nil
+		}
+		defer m.record("mkdir "+m.displayPath(p), fileOp{Kind: opMkdir, To: p})
+		if m.isLoaded(parent) {
+			insertChild(parent, &Node{Name: filepath.Base(p), Path: p, IsDir: true})
+			parent.Expanded = true
+		} else if err := expandIfNeeded(parent, m.query); err != nil {
+			m.status = "error: " + err.Error()
+			return nil
+		}
+		m.recompute()
+		m.reveal(p)
+		m.status = "created " + m.displayPath(p)
+		return nil
+	})
+}
+
+// renameSelected prompts for a new name for the selected note or directory
+// and, after confirmation, renames it on disk and in the tree. Note names keep
+// the allowedExts rules (".md" is appended when no extension is typed).
+func (m *model) renameSelected() {
+	cur := m.selected()
+	if cur == nil || m.isSavedSearch(cur) {
+		return
+	}
+	m.ask("rename "+cur.Name+" to: ", cur.Name, func(m *model, name string) tea.Cmd {
+		if !cur.IsDir {
+			n, err := noteFileName(name)
+			if err != nil {
+				m.status = "rename failed: " + err.Error()
+				return nil
+			}
+			name = n
+		}
+		if name == cur.Name {
+			return nil
+		}
+		m.confirm("rename "+cur.Name+" → "+name+"?", func(m *model) tea.Cmd {
+			oldName, oldPath := cur.Name, cur.Path
+			p, err := renameEntry(cur.Path, name)
+			if err != nil {
+				m.status = "rename failed: " + err.Error()
+				return nil
+			}
+			m.moveNode(cur, p)
+			m.status = "renamed " + oldName + " → " + cur.Name
+			m.record(m.status, fileOp{Kind: opRename, From: oldPath, To: p})
+			return nil
+		})
+		return nil
+	})
+}
+
+// moveSelected opens the directory picker and moves the selected entry into
+// the chosen directory. The node stays selected at its new location.
+func (m *model) moveSelected() {
+	cur := m.selected()
+	if cur == nil || m.isSavedSearch(cur) {
+		return
+	}
+	err := m.openPicker("move "+m.displayPath(cur.Path)+" to…", func(m *model, dir string) tea.Cmd {
+		oldPath := cur.Path
+		p, err := moveEntry(cur.Path, dir)
+		if err != nil {
+			m.status = "move failed: " + err.Error()
+			return nil
+		}
+		m.moveNode(cur, p)
+		m.status = "moved to " + m.displayPath(p)
+		m.record("move "+m.displayPath(oldPath)+" → "+m.displayPath(dir), fileOp{Kind: opMove, From: oldPath, To: p})
+		return nil
+	})
+	if err != nil {
+		m.status = "error: " + err.Error()
+	}
+}
+
+// deleteSelected asks for confirmation, then moves the selected note to the
+// trash or removes the selected directory if it is empty. Notes are never
+// deleted permanently from the tree view; purging happens in the trash view.
+func (m *model) deleteSelected() {
+	cur := m.selected()
+	if cur == nil || m.isSavedSearch(cur) {
+		return
+	}
+	if !cur.IsDir {
+		m.confirm("move "+m.displayPath(cur.Path)+" to trash?", func(m *model) tea.Cmd {
+			e, err := trashNote(cur.Path)
+			if err != nil {
+				m.status = "trash failed: " + err.Error()
+				return nil
+			}
+			if parent := parentOf(m.root, cur); parent != nil && parent.Kind == kindSearch {
+				_ = m.reload("") // listed in a saved search: drop it everywhere
+			} else if parent != nil {
+				removeChild(parent, cur)
+			}
+			m.recompute()
+			m.status = "moved " + cur.Name + " to trash (<X> to restore)"
+			m.record("trash "+m.displayPath(cur.Path), fileOp{Kind: opTrash, From: cur.Path, Trash: e.Name})
+			return nil
+		})
+		return
+	}
+	m.confirm("remove empty directory "+m.displayPath(cur.Path)+"?", func(m *model) tea.Cmd {
+		if err := removeEmptyDir(cur.Path); err != nil {
+			m.status = "remove failed: " + err.Error()
+			return nil
+		}
+		if parent := parentOf(m.root, cur); parent != nil {
+			removeChild(parent, cur)
+		}
+		m.recompute()
+		m.status = "removed " + m.displayPath(cur.Path)
+		m.record("rmdir "+m.displayPath(cur.Path), fileOp{Kind: opRmdir, From: cur.Path})
+		return nil
+	})
+}
+
+// moveNode re-homes n at newPath in the in-memory tree: it is detached from its
+// parent, its subtree paths are rewritten and it is inserted in sorted order
+// under the node for newPath's directory. The cursor follows the node.
+func (m *model) moveNode(n *Node, newPath string) {
+	parent := parentOf(m.root, n)
+	if parent != nil && parent.Kind == kindSearch {
+		// Listed in a saved search: the note's real node may be loaded too,
+		// so rebuild instead of patching both.
+		setPath(n, newPath)
+		_ = m.reload(newPath)
+		return
+	}
+	if parent != nil {
+		removeChild(parent, n)
+	}
+	setPath(n, newPath)
+	if dest := m.findDir(filepath.Dir(newPath)); dest != nil && m.isLoaded(dest) {
+		insertChild(dest, n)
+	}
+	m.recompute()
+	m.reveal(newPath)
+}
+
+// findDir returns the loaded directory node for path p, or nil.
+func (m *model) findDir(p string) *Node {
+	if p == m.root.Path {
+		return m.root
+	}
+	var walk func(n *Node) *Node
+	walk = func(n *Node) *Node {
+		for _, c := range n.Children {
+			if !c.IsDir {
+				continue
+			}
+			if c.Path == p {
+				return c
+			}
+			if strings.HasPrefix(p, c.Path+string(os.PathSeparator)) {
+				return walk(c)
+			}
+		}
+		return nil
+	}
+	return walk(m.root)
+}
+
+// openPeriodic opens the current period's note of the named kind, creating it
+// from its template first when needed.
+func (m *model) openPeriodic(kind string) tea.Cmd {
+	k, err := periodKindNamed(kind)
+	if err != nil {
+		m.status = err.Error()
+		return nil
+	}
+	p, created, err := ensurePeriodic(k, time.Now())
+	if err != nil {
+		m.status = kind + " note failed: " + err.Error()
+		return nil
+	}
+	if created {
+		m.record("create "+m.displayPath(p), fileOp{Kind: opCreate, To: p})
+	}
+	if cmd := m.openInEditor(p); cmd != nil {
+		return cmd
+	}
+	_ = m.reload(p)
+	return nil
+}
+
+// jumpPeriodic selects the closest existing periodic note before (dir < 0) or
+// after (dir > 0) the selected one, or around today when the selection is not
+// a periodic note.
+func (m *model) jumpPeriodic(dir int) {
+	from := ""
+	if cur := m.selected(); cur != nil {
+		from = cur.Path
+	}
+	p, err := findPeriodic(from, dir)
+	if err != nil {
+		m.status = "error: " + err.Error()
+		return
+	}
+	if p == "" {
+		m.status = "no more periodic notes in that direction"
+		return
+	}
+	if !m.reveal(p) {
+		m.status = m.displayPath(p) + " is hidden by the current filter"
+		return
+	}
+	m.status = helpText
+}
+
+// toggleMark adds the selected note to, or removes it from, the
+// multi-selection and advances the cursor so runs of notes are quick to mark.
+func (m *model) toggleMark() {
+	cur := m.selected()
+	if cur == nil || cur.IsDir {
+		return
+	}
+	if m.marked[cur.Path] {
+		delete(m.marked, cur.Path)
+	} else {
+		m.marked[cur.Path] = true
+	}
+	if m.cursor < len(m.visible)-1 {
+		m.cursor++
+		m.adjustScroll()
+	}
+	m.status = fmt.Sprintf("%d selected", len(m.markedPaths()))
+}
+
+// markedPaths returns the multi-selected notes that are still in the tree,
+// in tree order. A note listed both in an expanded saved search and in its
+// own directory is returned once.
+func (m *model) markedPaths() []string {
+	var out []string
+	seen := map[string]bool{}
+	var walk func(n *Node)
+	walk = func(n *Node) {
+		for _, c := range n.Children {
+			if c.IsDir {
+				walk(c)
+			} else if m.marked[c.Path] && !seen[c.Path] {
+				seen[c.Path] = true
+				out = append(out, c.Path)
+			}
+		}
+	}
+	walk(m.root)
+	return out
+}
+
+// splitSelected asks for a heading level, previews how the selected note
+// would be split and, on confirmation, writes the new notes.
+func (m *model) splitSelected() {
+	cur := m.selected()
+	if cur == nil || cur.IsDir {
+		m.status = "select a note to split"
+		return
+	}
+	src := cur.Path
+	m.ask("split "+cur.Name+" at heading level (1-6): ", "2", func(m *model, v string) tea.Cmd {
+		level, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil {
+			m.status = "split failed: invalid level " + strconv.Quote(v)
+			return nil
+		}
+		plan, err := planSplit(src, level)
+		if err != nil {
+			m.status = "split failed: " + err.Error()
+			return nil
+		}
+		m.showPager("split preview", plan.preview(), func(m *model) tea.Cmd {
+			ops, err := plan.apply()
+			if err != nil {
+				m.status = "split failed: " + err.Error()
+			} else {
+				m.status = fmt.Sprintf("split %s into %d notes", filepath.Base(src), len(plan.Parts))
+			}
+			if len(ops) > 0 {
+				m.record("split "+m.displayPath(src), ops...)
+			}
+			_ = m.reload(src)
+			return nil
+		})
+		return nil
+	})
+}
+
+// mergeMarked asks for a name, previews the concatenation of the
+// multi-selected notes and, on confirmation, writes the merged note next to
+// the first one. The sources are kept with <y> and moved to the trash with
+// <t> (as one undoable change either way).
+func (m *model) mergeMarked() {
+	paths := m.markedPaths()
+	if len(paths) < 2 {
+		m.status = "select at least two notes with <space> to merge"
+		return
+	}
+	dir := filepath.Dir(paths[0])
+	m.ask(fmt.Sprintf("merge %d notes into: ", len(paths)), "merged.md", func(m *model, name string) tea.Cmd {
+		name, err := noteFileName(name)
+		if err != nil {
+			m.status = "merge failed: " + err.Error()
+			return nil
+		}
+		body, err := planMerge(paths)
+		if err != nil {
+			m.status = "merge failed: " + err.Error()
+			return nil
+		}
+		target := filepath.Join(dir, name)
+		lines := []string{"merge into " + m.displayPath(target) + " (<y> keeps the sources, <t> moves them to the trash):"}
+		for _, p := range paths {
+			lines = append(lines, "  - "+m.displayPath(p))
+		}
+		lines = append(lines, "")
+		lines = append(lines, strings.Split(strings.TrimRight(body, "\n"), "\n")...)
+
+		merge := func(trash bool) func(m *model) tea.Cmd {
+			return func(m *model) tea.Cmd {
+				m.writeMerge(dir, name, body, paths, trash)
+				return nil
+			}
+		}
+		m.showPager("merge preview", lines, merge(false))
+		m.pager.keys = map[string]func(m *model) tea.Cmd{"t": merge(true)}
+		m.status = "↑/↓ scroll • <y> merge, keep sources • <t> merge, trash sources • <esc> cancel"
+		return nil
+	})
+}
+
+// writeMerge writes the merged note dir/name and, when trash is set, moves
+// the sources to the trash; everything done is recorded as one change.
+func (m *model) writeMerge(dir, name, body string, paths []string, trash bool) {
+	p, err := writeNewNote(dir, name, body)
+	if err != nil {
+		m.status = "merge failed: " + err.Error()
+		return
+	}
+	ops := []fileOp{{Kind: opCreate, To: p}}
+	m.status = fmt.Sprintf("merged %d notes into %s", len(paths), m.displayPath(p))
+	for i := 0; trash && i < len(paths); i++ {
+		e, err := trashNote(paths[i])
+		if err != nil {
+			m.status = "merge: trash failed: " + err.Error()
+			break
+		}
+		ops = append(ops, fileOp{Kind: opTrash, From: paths[i], Trash: e.Name})
+	}
+	m.marked = map[string]bool{}
+	m.record("merge into "+m.displayPath(p), ops...)
+	_ = m.reload(p)
+}
+
+// record logs a completed change for undo/redo. Failing to write the log does
+// not fail the operation itself; a warning is appended to the status line.
+func (m *model) record(label string, ops ...fileOp) {
+	if err := recordChange(label, ops...); err != nil {
+		m.status += " (undo log: " + err.Error() + ")"
+	}
+}
+
+// undo reverts the last logged change, or re-applies the last undone change
+// when redo is set, then reloads the tree and selects the affected path.
+func (m *model) undo(redo bool) {
+	verb, apply := "undid", undoLast
+	if redo {
+		verb, apply = "redid", redoLast
+	}
+	c, focus, err := apply()
+	switch {
+	case c.Label == "":
+		m.status = err.Error()
+		return
+	case err != nil:
+		// Failed and rolled back, partly skipped, or not saved; files may
+		// have changed either way.
+		m.status = err.Error()
+	default:
+		m.status = verb + ": " + c.Label
+	}
+	if err := m.reload(focus); err != nil {
+		m.status = "reload failed: " + err.Error()
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultArchiveDir is where `nnav archive` moves old notes, relative to the
+// notes root, when "archivedir" is not set in ~/.nnav.
+const defaultArchiveDir = "archive"
+
+// ageRE matches ages such as "180d", "12w", "6m" (months) or "1y".
+var ageRE = regexp.MustCompile(`^(\d+)([dwmy])$`)
+
+// archiveMove is one note scheduled for archiving.
+//   - From: current path of the note.
+//   - To: destination inside the archive, mirroring the note's relative path.
+//   - ModTime: last modification time that made the note eligible.
+type archiveMove struct {
+	From    string
+	To      string
+	ModTime time.Time
+}
+
+// archiveDir returns the archive directory inside the notes root. The
+// "archivedir" setting is relative to notesdir and validated with
+// safeJoinWithin, so the archive can never live outside the notes tree.
+func archiveDir() (string, error) {
+	root, err := notesRoot()
+	if err != nil {
+		return "", err
+	}
+	cfg, _ := loadConfig()
+	rel := strings.TrimSpace(cfg["archivedir"])
+	if rel == "" {
+		rel = defaultArchiveDir
+	}
+	rel = filepath.Clean(rel)
+	if rel == "." {
+		return "", errors.New("archivedir must be a subdirectory of notesdir")
+	}
+	if _, err := safeJoinWithin(root, rel); err != nil {
+		return "", fmt.Errorf("invalid archivedir: %w", err)
+	}
+	return filepath.Join(root, rel), nil
+}
+
+// ageCutoff turns an age such as "180d" into the point in time it reaches
+// back to from now. Months and years are calendar-based (AddDate).
+func ageCutoff(age string, now time.Time) (time.Time, error) {
+	m := ageRE.FindStringSubmatch(strings.ToLower(strings.TrimSpace(age)))
+	if m == nil {
+		return time.Time{}, fmt.Errorf("invalid age: %q (use e.g. 180d, 12w, 6m or 1y)", age)
+	}
+	n, err := strconv.Atoi(m[1])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid age: %q", age)
+	}
+	switch m[2] {
+	case "d":
+		return now.AddDate(0, 0, -n), nil
+	case "w":
+		return now.AddDate(0, 0, -7*n), nil
+	case "m":
+		return now.AddDate(0, -n, 0), nil
+	default: // y
+		return now.AddDate(-n, 0, 0), nil
+	}
+}
+
+// countNotes formats n as "1 note" or "n notes" for messages.
+func countNotes(n int) string {
+	if n == 1 {
+		return "1 note"
+	}
+	return fmt.Sprintf("%d notes", n)
+}
+
+// inSubtree reports whether p is dir itself or lies below it.
+func inSubtree(p, dir string) bool {
+	return p == dir || strings.HasPrefix(p, dir+string(os.PathSeparator))
+}
+
+// planArchive lists the notes below dir last modified before cutoff, with
+// their mirrored destination inside archive. The walk goes through
+// readDirNodes (see walkNotes), so exactly the notes shown in the TUI are
+// considered (trash, templates, unreadable entries are skipped); the archive
+// itself is never descended into.
+func planArchive(root, dir, archive string, cutoff time.Time) ([]archiveMove, error) {
+	var moves []archiveMove
+	skip := func(p string) bool { return inSubtree(p, archive) }
+	err := walkNotes(dir, skip, func(n *Node) error {
+		if !n.ModTime.Before(cutoff) {
+			return nil
+		}
+		rel, err := filepath.Rel(root, n.Path)
+		if err != nil {
+			return err
+		}
+		moves = append(moves, archiveMove{From: n.Path, To: filepath.Join(archive, rel), ModTime: n.ModTime})
+		return nil
+	})
+	return moves, err
+}
+
+// applyArchive performs the planned moves, creating the mirrored directories
+// on the way. It returns the ops done so far even on error, so a partial
+// archive run can still be undone.
+func applyArchive(moves []archiveMove) ([]fileOp, error) {
+	var ops []fileOp
+	for _, mv := range moves {
+		mk, err := ensureDirs(filepath.Dir(mv.To))
+		ops = append(ops, mk...)
+		if err != nil {
+			return ops, err
+		}
+		p, err := moveEntry(mv.From, filepath.Dir(mv.To))
+		if err != nil {
+			return ops, err
+		}
+		ops = append(ops, fileOp{Kind: opMove, From: mv.From, To: p})
+	}
+	return ops, nil
+}
+
+// cmdArchive implements `nnav archive --older-than <age> [--dir <dir>] [--dry-run]`:
+// move notes that were not modified for the given age into the archive,
+// keeping their relative path (projects/x.md → archive/projects/x.md).
+// The run is logged as a single change, so `u` in the TUI undoes it.
+func cmdArchive(args []string) error {
+	fs := flag.NewFlagSet("archive", flag.ContinueOnError)
+	olderThan := fs.String("older-than", "", "archive notes not modified for this long (e.g. 180d, 12w, 6m, 1y)")
+	dir := fs.String("dir", "", "only archive notes below this directory, relative to the notes dir")
+	dryRun := fs.Bool("dry-run", false, "list the notes that would be archived without moving them")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: nnav archive --older-than <age> [--dir <dir>] [--dry-run]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 || *olderThan == "" {
+		fs.Usage()
+		return errors.New("--older-than is required")
+	}
+	cutoff, err := ageCutoff(*olderThan, time.Now())
+	if err != nil {
+		return err
+	}
+	root, err := notesRoot()
+	if err != nil {
+		return err
+	}
+	archive, err := archiveDir()
+	if err != nil {
+		return err
+	}
+
+	from, err := notesSubdir(*dir)
+	if err != nil {
+		return err
+	}
+	if inSubtree(from, archive) {
+		return errors.New("--dir points into the archive")
+	}
+
+	moves, err := planArchive(root, from, archive, cutoff)
+	if err != nil {
+		return err
+	}
+	if len(moves) == 0 {
+		fmt.Println("nothing to archive")
+		return nil
+	}
+	rel := func(p string) string {
+		r, _ := filepath.Rel(root, p)
+		return r
+	}
+	if *dryRun {
+		for _, mv := range moves {
+			fmt.Printf("%s → %s (modified %s)\n", rel(mv.From), rel(mv.To), mv.ModTime.Format("2006-01-02"))
+		}
+		fmt.Printf("%s would be archived\n", countNotes(len(moves)))
+		return nil
+	}
+
+	ops, err := applyArchive(moves)
+	moved := 0
+	for _, op := range ops {
+		if op.Kind == opMove {
+			fmt.Printf("archived %s\n", rel(op.From))
+			moved++
+		}
+	}
+	if len(ops) > 0 {
+		label := "archive " + countNotes(moved)
+		if rerr := recordChange(label, ops...); rerr != nil {
+			fmt.Fprintln(os.Stderr, "nnav: undo log:", rerr)
+		}
+	}
+	return err
+}
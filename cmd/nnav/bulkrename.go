@@ -0,0 +1,332 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// renamePlaceholderRE finds the placeholders of a rename pattern:
+// {{name}}, {{title}} and {{n}} / {{n:3}}, each optionally followed by
+// filters such as {{title|slug}} or {{name|lower}}.
+var renamePlaceholderRE = regexp.MustCompile(`\{\{\s*(name|title|n)(?::(\d+))?((?:\s*\|\s*[a-z]+)*)\s*\}\}`)
+
+// renameSpec describes how a batch of notes is renamed.
+//   - re/repl: with re set, repl replaces every match in the current file
+//     stem ("s/RE/REPL/"); otherwise repl is the whole new stem.
+//   - start: sequence number given to the first note ({{n}}).
+//   - lower/slug: applied to the resulting stem.
+//
+// The extension of each note is always kept.
+type renameSpec struct {
+	re    *regexp.Regexp
+	repl  string
+	start int
+	lower bool
+	slug  bool
+}
+
+// renameItem is one line of a bulk rename plan. Problem is non-empty when
+// the rename cannot be done (collision, invalid name); Same marks notes whose
+// name does not change and Note adds a remark to the preview line.
+type renameItem struct {
+	From    string
+	To      string
+	Problem string
+	Same    bool
+	Note    string
+}
+
+// parseRenameSpec parses a pattern typed by the user. "s/RE/REPL/" (the
+// delimiter may also be | # : or ,; a trailing "i" makes RE case-insensitive)
+// substitutes within the current name; anything else is a template for the
+// new name.
+func parseRenameSpec(pattern string) (renameSpec, error) {
+	spec := renameSpec{repl: pattern, start: 1}
+	if strings.TrimSpace(pattern) == "" {
+		return spec, errors.New("empty rename pattern")
+	}
+	if len(pattern) < 2 || pattern[0] != 's' || !strings.ContainsRune("/|#:,", rune(pattern[1])) {
+		return spec, nil
+	}
+	parts := strings.Split(pattern[2:], pattern[1:2])
+	if len(parts) != 3 || (parts[2] != "" && parts[2] != "i") {
+		return spec, fmt.Errorf("invalid substitution: %q (use s/RE/REPL/)", pattern)
+	}
+	expr := parts[0]
+	if parts[2] == "i" {
+		expr = "(?i)" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return spec, fmt.Errorf("invalid regex: %w", err)
+	}
+	spec.re, spec.repl = re, parts[1]
+	return spec, nil
+}
+
+// newStem computes the new stem of note p, the i-th note of the batch.
+// Placeholder values have "$" escaped so they are never read as regex group
+// references, and path separators replaced so a title cannot create paths.
+func (s renameSpec) newStem(p string, i int) string {
+	stem := strings.TrimSuffix(filepath.Base(p), filepath.Ext(p))
+	var title string
+	titled := false
+	repl := renamePlaceholderRE.ReplaceAllStringFunc(s.repl, func(ph string) string {
+		m := renamePlaceholderRE.FindStringSubmatch(ph)
+		var v string
+		switch m[1] {
+		case "name":
+			v = stem
+		case "title":
+			if !titled {
+				title, _ = scanTitle(p, nil)
+				if title = strings.TrimSpace(title); title == "" {
+					_, rest := splitNoteID(stem)
+					title = rest
+				}
+				titled = true
+			}
+			v = title
+		default: // n
+			v = strconv.Itoa(s.start + i)
+			if w, _ := strconv.Atoi(m[2]); len(v) < w {
+				v = strings.Repeat("0", w-len(v)) + v
+			}
+		}
+		for _, f := range strings.Split(m[3], "|") {
+			switch strings.TrimSpace(f) {
+			case "lower":
+				v = strings.ToLower(v)
+			case "upper":
+				v = strings.ToUpper(v)
+			case "slug":
+				v = slugify(v)
+			}
+		}
+		v = strings.NewReplacer("/", "-", "\\", "-").Replace(v)
+		if s.re != nil {
+			v = strings.ReplaceAll(v, "$", "$$")
+		}
+		return v
+	})
+	out := repl
+	if s.re != nil {
+		out = s.re.ReplaceAllString(stem, repl)
+	}
+	if s.lower {
+		out = strings.ToLower(out)
+	}
+	if s.slug {
+		out = slugify(out)
+	}
+	return strings.TrimSpace(out)
+}
+
+// planRename computes the new name of every note in paths (in order, which
+// also drives {{n}}) and flags problems: invalid names, two notes getting the
+// same name, and names already taken on disk. Nothing is renamed here.
+func planRename(paths []string, spec renameSpec) []renameItem {
+	items := make([]renameItem, len(paths))
+	seen := map[string]int{}
+	for i, p := range paths {
+		it := renameItem{From: p}
+		name := spec.newStem(p, i) + filepath.Ext(p)
+		it.To = filepath.Join(filepath.Dir(p), name)
+		switch {
+		case validateEntryName(name) != nil || strings.HasPrefix(name, "."):
+			it.Problem = "invalid name"
+		case it.To == p:
+			it.Same = true
+		default:
+			if j, dup := seen[it.To]; dup {
+				it.Problem = "same name as " + filepath.Base(paths[j])
+				break
+			}
+			seen[it.To] = i
+			if _, safe, err := safeTarget(filepath.Dir(p), name); err != nil {
+				it.Problem = err.Error()
+			} else if _, err := os.Lstat(safe); err == nil {
+				it.Problem = "already exists"
+			}
+		}
+		items[i] = it
+	}
+	return items
+}
+
+// renameProblems counts the items of a plan that cannot be renamed.
+func renameProblems(items []renameItem) int {
+	n := 0
+	for _, it := range items {
+		if it.Problem != "" {
+			n++
+		}
+	}
+	return n
+}
+
+// renamePreview renders the plan side by side (old → new), names relative to
+// their directory, with collisions and other problems flagged with ✗.
+func renamePreview(items []renameItem, rel func(p string) string) []string {
+	width := 0
+	for _, it := range items {
+		width = max(width, len([]rune(rel(it.From))))
+	}
+	lines := make([]string, 0, len(items)+2)
+	for _, it := range items {
+		old := rel(it.From)
+		line := old + strings.Repeat(" ", width-len([]rune(old))) + "  →  " + filepath.Base(it.To)
+		switch {
+		case it.Problem != "":
+			line += "   ✗ " + it.Problem
+		case it.Same:
+			line += "   (unchanged)"
+		case it.Note != "":
+			line += "   (" + it.Note + ")"
+		}
+		lines = append(lines, line)
+	}
+	if n := renameProblems(items); n > 0 {
+		lines = append(lines, "", fmt.Sprintf("%d problem(s): nothing will be renamed", n))
+	}
+	return lines
+}
+
+// applyRename renames every item of a problem-free plan and returns the ops
+// done so far, also on error, so a partial run can still be undone.
+func applyRename(items []renameItem) ([]fileOp, error) {
+	var ops []fileOp
+	for _, it := range items {
+		if it.Same {
+			continue
+		}
+		p, err := renameEntry(it.From, filepath.Base(it.To))
+		if err != nil {
+			return ops, fmt.Errorf("%s: %w", filepath.Base(it.From), err)
+		}
+		ops = append(ops, fileOp{Kind: opRename, From: it.From, To: p})
+	}
+	return ops, nil
+}
+
+// cmdRename implements `nnav rename [flags] <pattern> <note>...`.
+// Notes are paths relative to the current directory (so shell globs work) and
+// must lie inside the notes dir. The preview is always printed; the renames
+// happen after confirmation (or --yes) and only when there are no problems.
+func cmdRename(args []string) error {
+	fs := flag.NewFlagSet("rename", flag.ContinueOnError)
+	start := fs.Int("start", 1, "first sequence number for {{n}}")
+	lower := fs.Bool("lower", false, "lowercase the new names")
+	slug := fs.Bool("slug", false, "slugify the new names (lowercase, dashes)")
+	dryRun := fs.Bool("dry-run", false, "only print the preview")
+	yes := fs.Bool("yes", false, "rename without asking for confirmation")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), `usage: nnav rename [flags] <pattern> <note>...
+  pattern: a template such as "{{n:3}}-{{title|slug}}" ({{name}}, {{title}}, {{n}},
+           filters |lower |upper |slug) or a substitution "s/RE/REPL/" on the name`)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() < 2 {
+		fs.Usage()
+		return errors.New("missing pattern or notes")
+	}
+	spec, err := parseRenameSpec(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	spec.start, spec.lower, spec.slug = *start, *lower, *slug
+
+	root, err := notesRoot()
+	if err != nil {
+		return err
+	}
+	var paths []string
+	for _, a := range fs.Args()[1:] {
+		p, err := filepath.Abs(a)
+		if err != nil {
+			return err
+		}
+		if _, ok := safePathWithinNotes(p); !ok || p == root {
+			return fmt.Errorf("not a note inside the notes dir: %s", a)
+		}
+		if !allowedExts[strings.ToLower(filepath.Ext(p))] || !isReadableFile(p) {
+			return fmt.Errorf("not a readable note: %s", a)
+		}
+		paths = append(paths, p)
+	}
+
+	items := planRename(paths, spec)
+	rel := func(p string) string {
+		r, _ := filepath.Rel(root, p)
+		return r
+	}
+	for _, line := range renamePreview(items, rel) {
+		fmt.Println(line)
+	}
+	if n := renameProblems(items); n > 0 {
+		return fmt.Errorf("%d problem(s), nothing renamed", n)
+	}
+	if *dryRun {
+		return nil
+	}
+	if !*yes && !askYes("rename "+countNotes(len(items))+"?") {
+		return errors.New("cancelled")
+	}
+	ops, err := applyRename(items)
+	if len(ops) > 0 {
+		if rerr := recordChange("rename "+countNotes(len(ops)), ops...); rerr != nil {
+			fmt.Fprintln(os.Stderr, "nnav: undo log:", rerr)
+		}
+		fmt.Printf("renamed %s\n", countNotes(len(ops)))
+	}
+	return err
+}
+
+// bulkRenameMarked renames all notes selected with <space>: it asks for a
+// pattern, shows the old → new preview and applies it on confirmation.
+// Plans with problems are shown read-only.
+func (m *model) bulkRenameMarked() {
+	paths := m.markedPaths()
+	m.ask("rename "+countNotes(len(paths))+" to pattern: ", "{{name}}", func(m *model, pattern string) tea.Cmd {
+		spec, err := parseRenameSpec(pattern)
+		if err != nil {
+			m.status = "rename failed: " + err.Error()
+			return nil
+		}
+		items := planRename(paths, spec)
+		lines := renamePreview(items, m.displayPath)
+		if renameProblems(items) > 0 {
+			m.showPager("bulk rename preview", lines, nil)
+			return nil
+		}
+		m.showPager("bulk rename preview", lines, func(m *model) tea.Cmd {
+			ops, err := applyRename(items)
+			m.status = "renamed " + countNotes(len(ops))
+			if err != nil {
+				m.status = "rename failed: " + err.Error()
+			}
+			if len(ops) > 0 {
+				m.record("rename "+countNotes(len(ops)), ops...)
+			}
+			m.marked = map[string]bool{}
+			focus := ""
+			if len(ops) > 0 {
+				focus = ops[0].To
+			}
+			_ = m.reload(focus)
+			return nil
+		})
+		return nil
+	})
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestParseRenameSpec(t *testing.T) {
+	tests := []struct {
+		pattern string
+		re      string // "" for a template
+		repl    string
+		wantErr bool
+	}{
+		{"{{n:3}}-{{title|slug}}", "", "{{n:3}}-{{title|slug}}", false},
+		{"s/draft-//", "draft-", "", false},
+		{"s|a/b|c|", "a/b", "c", false},
+		{"s/Draft/final/i", "(?i)Draft", "final", false},
+		{"s#(\\d+)#n$1#", "(\\d+)", "n$1", false},
+		{"summary", "", "summary", false}, // no delimiter after the s
+		{"s", "", "s", false},
+		{"", "", "", true},
+		{"   ", "", "", true},
+		{"s/a/b", "", "", true},
+		{"s/a/b/c/", "", "", true},
+		{"s/a/b/g", "", "", true},
+		{"s/(/x/", "", "", true},
+	}
+	for _, tt := range tests {
+		spec, err := parseRenameSpec(tt.pattern)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseRenameSpec(%q) error = %v, want error %v", tt.pattern, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		re := ""
+		if spec.re != nil {
+			re = spec.re.String()
+		}
+		if re != tt.re || spec.repl != tt.repl || spec.start != 1 {
+			t.Errorf("parseRenameSpec(%q) = re %q, repl %q, start %d; want %q, %q, 1", tt.pattern, re, spec.repl, spec.start, tt.re, tt.repl)
+		}
+	}
+}
+
+func TestPlanRename(t *testing.T) {
+	root := testNotes(t, map[string]string{
+		"a/Draft One.md":  "# First Idea\n",
+		"a/draft-two.txt": "no heading\n",
+		"a/taken.md":      "",
+		"b/dollar.md":     "# Costs $5 / month\n",
+	})
+	p := func(rel string) string { return filepath.Join(root, filepath.FromSlash(rel)) }
+
+	tests := []struct {
+		name    string
+		pattern string
+		lower   bool
+		paths   []string
+		want    []string // new base names
+		problem []string // expected Problem per item
+	}{
+		{
+			name:    "template with counter and title",
+			pattern: "{{n:2}}-{{title|slug}}",
+			paths:   []string{p("a/Draft One.md"), p("a/draft-two.txt")},
+			want:    []string{"01-first-idea.md", "02-draft-two.txt"},
+			problem: []string{"", ""},
+		},
+		{
+			name:    "substitution keeps the extension",
+			pattern: "s/draft[- ]//i",
+			paths:   []string{p("a/Draft One.md"), p("a/draft-two.txt")},
+			want:    []string{"One.md", "two.txt"},
+			problem: []string{"", ""},
+		},
+		{
+			name:    "title with separators and dollar",
+			pattern: "s/.*/{{title}}/",
+			paths:   []string{p("b/dollar.md")},
+			want:    []string{"Costs $5 - month.md"},
+			problem: []string{""},
+		},
+		{
+			name:    "lower",
+			pattern: "{{name}}",
+			lower:   true,
+			paths:   []string{p("a/Draft One.md")},
+			want:    []string{"draft one.md"},
+			problem: []string{""},
+		},
+		{
+			name:    "unchanged",
+			pattern: "{{name}}",
+			paths:   []string{p("a/taken.md")},
+			want:    []string{"taken.md"},
+			problem: []string{""},
+		},
+		{
+			name:    "same name in different dirs",
+			pattern: "same",
+			paths:   []string{p("a/Draft One.md"), p("a/draft-two.txt"), p("b/dollar.md")},
+			want:    []string{"same.md", "same.txt", "same.md"},
+			problem: []string{"", "", ""},
+		},
+		{
+			name:    "two notes, one name",
+			pattern: "s/.*/x/",
+			paths:   []string{p("a/Draft One.md"), p("a/taken.md")},
+			want:    []string{"x.md", "x.md"},
+			problem: []string{"", "same name as Draft One.md"},
+		},
+		{
+			name:    "existing note",
+			pattern: "taken",
+			paths:   []string{p("a/Draft One.md")},
+			want:    []string{"taken.md"},
+			problem: []string{"already exists"},
+		},
+		{
+			name:    "hidden name",
+			pattern: "s/.*/.hidden/",
+			paths:   []string{p("a/Draft One.md")},
+			want:    []string{".hidden.md"},
+			problem: []string{"invalid name"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec, err := parseRenameSpec(tt.pattern)
+			if err != nil {
+				t.Fatal(err)
+			}
+			spec.lower = tt.lower
+			items := planRename(tt.paths, spec)
+			for i, it := range items {
+				if it.From != tt.paths[i] || filepath.Dir(it.To) != filepath.Dir(it.From) {
+					t.Errorf("item %d moves %s to %s", i, it.From, it.To)
+				}
+				if got := filepath.Base(it.To); got != tt.want[i] {
+					t.Errorf("item %d: new name %q, want %q", i, got, tt.want[i])
+				}
+				if it.Problem != tt.problem[i] {
+					t.Errorf("item %d: problem %q, want %q", i, it.Problem, tt.problem[i])
+				}
+				if it.Same != (it.To == it.From) {
+					t.Errorf("item %d: Same = %v", i, it.Same)
+				}
+			}
+			if n := renameProblems(items); n != countNonEmpty(tt.problem) {
+				t.Errorf("renameProblems = %d", n)
+			}
+		})
+	}
+}
+
+func countNonEmpty(ss []string) int {
+	n := 0
+	for _, s := range ss {
+		if s != "" {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// defaultInbox is the capture target (relative to the notes root) when
+// "inbox" is not set in ~/.nnav.
+const defaultInbox = "inbox.md"
+
+// lockTimeout bounds how long a capture waits for another writer.
+const lockTimeout = 5 * time.Second
+
+// cmdAdd implements `nnav add [--to <note>] [text...]`.
+// The entry text comes from the arguments or, when none are given, from stdin
+// (e.g. `echo text | nnav add`). It is appended to the inbox note as a
+// timestamped list item without starting the TUI or an editor.
+func cmdAdd(args []string) error {
+	fs := flag.NewFlagSet("add", flag.ContinueOnError)
+	to := fs.String("to", "", "note to append to, relative to the notes dir (default: inbox)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: nnav add [--to <note>] [text...]   (reads stdin when no text is given)")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	text := strings.Join(fs.Args(), " ")
+	if text == "" {
+		info, err := os.Stdin.Stat()
+		if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeCharDevice != 0 {
+			fs.Usage()
+			return errors.New("nothing to add")
+		}
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		text = string(data)
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return errors.New("nothing to add")
+	}
+
+	target := strings.TrimSpace(*to)
+	if target == "" {
+		cfg, _ := loadConfig()
+		target = strings.TrimSpace(cfg["inbox"])
+		if target == "" {
+			target = defaultInbox
+		}
+	}
+	p, err := captureTarget(target)
+	if err != nil {
+		return err
+	}
+	return appendEntry(p, text, time.Now())
+}
+
+// captureTarget resolves a note name relative to the notes root with
+// safeJoinWithin; ".md" is appended when the name has no extension. Parent
+// directories that already exist are resolved through their symlinks, so a
+// link inside the notes dir cannot redirect the capture outside of it.
+func captureTarget(name string) (string, error) {
+	if filepath.Ext(name) == "" {
+		name += defaultNoteExt
+	}
+	if !allowedExts[strings.ToLower(filepath.Ext(name))] {
+		return "", fmt.Errorf("unsupported extension: %q (use .md or .txt)", filepath.Ext(name))
+	}
+	root, err := notesRoot()
+	if err != nil {
+		return "", err
+	}
+	return safeJoinWithin(root, name)
+}
+
+// formatEntry renders a capture as a Markdown list item:
+// "- 2006-01-02 15:04 text", with continuation lines indented under it.
+func formatEntry(text string, now time.Time) string {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	var b strings.Builder
+	b.WriteString("- " + now.Format("2006-01-02 15:04") + " " + lines[0] + "\n")
+	for _, l := range lines[1:] {
+		b.WriteString("  " + l + "\n")
+	}
+	return b.String()
+}
+
+// appendEntry appends a formatted entry to the note at p (already validated).
+// An exclusive lock serializes concurrent captures, and the note is rewritten
+// with writeFileAtomic so readers never observe a half-written file. The note
+// (and its parent directories) are created with private permissions if missing.
+func appendEntry(p, text string, now time.Time) error {
+	if err := os.MkdirAll(filepath.Dir(p), 0o700); err != nil {
+		return err
+	}
+	unlock, err := lockNote(p)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	perm := os.FileMode(0o600)
+	// #nosec G304 -- p is validated by safeJoinWithin in captureTarget.
+	data, err := os.ReadFile(p)
+	switch {
+	case err == nil:
+		if info, err := os.Stat(p); err == nil {
+			perm = info.Mode().Perm()
+		}
+		if len(data) > 0 && data[len(data)-1] != '\n' {
+			data = append(data, '\n')
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return err
+	}
+	data = append(data, formatEntry(text, now)...)
+	return writeFileAtomic(p, data, perm)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// chooserHelp is the footer shown while a chooser is open.
+const chooserHelp = "↑/↓ move • <enter> choose • <esc> cancel"
+
+// chooser is a modal single-choice list (e.g. picking a template).
+//   - title: heading describing the choice.
+//   - items: labels shown one per row.
+//   - onChoose: called with the index of the chosen item.
+type chooser struct {
+	title    string
+	items    []string
+	cursor   int
+	scroll   int
+	onChoose func(m *model, i int) tea.Cmd
+}
+
+// choose opens a chooser over items.
+func (m *model) choose(title string, items []string, onChoose func(m *model, i int) tea.Cmd) {
+	m.chooser = &chooser{title: title, items: items, onChoose: onChoose}
+	m.status = chooserHelp
+}
+
+// updateChooser handles keys while a chooser is open.
+func (m model) updateChooser(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	c := m.chooser
+	switch msg.String() {
+	case "esc", "q", "ctrl+c":
+		m.chooser = nil
+		m.status = "cancelled"
+
+	case "down", "j":
+		if c.cursor < len(c.items)-1 {
+			c.cursor++
+		}
+
+	case "up", "k":
+		if c.cursor > 0 {
+			c.cursor--
+		}
+
+	case "enter":
+		m.chooser = nil
+		m.status = helpText
+		return m, c.onChoose(&m, c.cursor)
+	}
+	c.scroll = scrollWindow(c.cursor, c.scroll, len(c.items), m.height)
+	return m, nil
+}
+
+// view renders the chooser using the shared frame layout.
+func (c *chooser) view(m model) string {
+	return m.frame(c.title, len(c.items), func(i int) string {
+		return "• " + c.items[i]
+	}, c.cursor, c.scroll)
+}
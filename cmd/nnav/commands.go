@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// subcommands maps the first command-line argument to its handler.
+// Any other first argument is treated as a search term for the TUI (see main);
+// "--" forces a search for a word that is also a subcommand.
+var subcommands = map[string]func(args []string) error{
+	"today":     func(args []string) error { return cmdPeriodic("today", "daily", args) },
+	"week":      func(args []string) error { return cmdPeriodic("week", "weekly", args) },
+	"month":     func(args []string) error { return cmdPeriodic("month", "monthly", args) },
+	"add":       cmdAdd,
+	"id":        cmdID,
+	"archive":   cmdArchive,
+	"rename":    cmdRename,
+	"normalize": cmdNormalize,
+	"index":     cmdIndex,
+}
+
+// subcommandNames returns the subcommand names, sorted, for usage messages.
+func subcommandNames() []string {
+	names := make([]string, 0, len(subcommands))
+	for name := range subcommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// cmdPeriodic implements `nnav today|week|month`: open (creating if needed)
+// the periodic note for the current period in the editor.
+func cmdPeriodic(cmd, kind string, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("usage: nnav %s", cmd)
+	}
+	k, err := periodKindNamed(kind)
+	if err != nil {
+		return err
+	}
+	p, created, err := ensurePeriodic(k, time.Now())
+	if err != nil {
+		return err
+	}
+	if created {
+		root, err := notesRoot()
+		if err != nil {
+			return err
+		}
+		if err := recordChange("create "+rootedPath(root, p), fileOp{Kind: opCreate, To: p}); err != nil {
+			fmt.Fprintln(os.Stderr, "nnav: undo log:", err)
+		}
+	}
+	return editFile(p)
+}
+
+// notesSubdir resolves the --dir flag of a subcommand: a directory relative to
+// the notes root, validated with safeJoinWithin. Empty means the root itself.
+func notesSubdir(dir string) (string, error) {
+	root, err := notesRoot()
+	if err != nil {
+		return "", err
+	}
+	dir = strings.TrimSpace(dir)
+	if dir == "" {
+		return root, nil
+	}
+	if _, err := safeJoinWithin(root, dir); err != nil {
+		return "", fmt.Errorf("invalid --dir: %w", err)
+	}
+	p := filepath.Join(root, filepath.Clean(dir))
+	if !isListableDir(p) {
+		return "", fmt.Errorf("not a readable directory: %s", dir)
+	}
+	return p, nil
+}
+
+// askYes prints question and reads a yes/no answer from stdin (default no).
+func askYes(question string) bool {
+	fmt.Print(question + " [y/N] ")
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	a := strings.ToLower(strings.TrimSpace(answer))
+	return a == "y" || a == "yes"
+}
+
+// editFile opens p in the configured editor outside of the TUI, applying the
+// same editor allowlist and notes-root validation as the TUI.
+func editFile(p string) error {
+	edPath, edArgs, err := resolveEditor()
+	if err != nil {
+		return err
+	}
+	root, err := notesRoot()
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(root, p)
+	if err != nil {
+		return err
+	}
+	safe, err := safeJoinWithin(root, rel)
+	if err != nil {
+		return err
+	}
+	return execCommand(edPath, append(edArgs, safe)...).Run()
+}
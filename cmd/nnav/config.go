@@ -41,8 +41,16 @@ func ensureConfig() (string, error) {
 		_, _ = f.WriteString(`# nnav configuration
 # notesdir: path to your notes directory (e.g., ~/notes). Must be readable by your user.
 # editor: which editor to launch. Allowed values: vim, nvim, vi, nano, hx, emacs
+# trash: where deleted notes go: notes (<notesdir>/.nnav-trash) or freedesktop (~/.local/share/Trash)
+# templatesdir: note templates offered by <n> (default: <notesdir>/.templates)
+# archivedir: where "nnav archive" moves old notes, relative to notesdir (default: archive)
+# inbox: note that "nnav add" appends to, relative to notesdir (default: inbox.md; a file, unlike inboxdir)
+# inboxdir: directory stepped through by the inbox triage mode <I>, relative to notesdir (default: inbox)
+# naming: plain (type the file name) or id (type a title, file becomes <YYYYMMDDhhmm>-<slug>.md)
+# search.<id>: saved search shown as a virtual folder at the top of the tree, as Name|query (e.g. search.incidents=Open incidents|incident -resolved)
 notesdir=~/notes
 editor=vim
+trash=notes
 `)
 	} else if err == nil {
 		// Config file exists → ensure permissions are still locked down.
@@ -121,4 +129,3 @@ func notesRoot() (string, error) {
 	}
 	return filepath.Join(home, defaultNotesSubdir), nil
 }
-
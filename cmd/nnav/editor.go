@@ -21,11 +21,11 @@ var allowedEditors = map[string]bool{
 // resolveEditor chooses which editor to launch when opening notes.
 //
 // Logic:
-//   1. Read `editor` from ~/.nnav config (default to "vim" if unset).
-//   2. Validate it is a *bare command name* (no slashes, spaces, or paths).
-//      - Prevents users from setting dangerous values like "vim; rm -rf /" or "/usr/bin/vim".
-//   3. Check it is in the `allowedEditors` list to ensure predictable UX.
-//   4. Verify it exists in $PATH (via exec.LookPath).
+//  1. Read `editor` from ~/.nnav config (default to "vim" if unset).
+//  2. Validate it is a *bare command name* (no slashes, spaces, or paths).
+//     - Prevents users from setting dangerous values like "vim; rm -rf /" or "/usr/bin/vim".
+//  3. Check it is in the `allowedEditors` list to ensure predictable UX.
+//  4. Verify it exists in $PATH (via exec.LookPath).
 //
 // Returns: full binary path, no arguments (currently unused slice), or error.
 func resolveEditor() (string, []string, error) {
@@ -55,3 +55,19 @@ func resolveEditor() (string, []string, error) {
 	return path, nil, nil
 }
 
+// editorFileArgs returns the arguments that open file at line (1-based) in
+// the editor at edPath: "+N file" for vim, nvim, vi, nano and emacs, and
+// "file:N" for helix. A line below 1, or an editor not known to take a line
+// (which could read "+N" as a file name), opens the file at the top.
+func editorFileArgs(edPath, file string, line int) []string {
+	if line < 1 {
+		return []string{file}
+	}
+	switch filepath.Base(edPath) {
+	case "vim", "nvim", "vi", "nano", "emacs":
+		return []string{fmt.Sprintf("+%d", line), file}
+	case "hx":
+		return []string{fmt.Sprintf("%s:%d", file, line)}
+	}
+	return []string{file}
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// defaultNoteExt is appended to new note names typed without an extension.
+const defaultNoteExt = ".md"
+
+// validateEntryName checks a user-typed file or directory name.
+// Only bare names are accepted: path separators, "." and ".." are rejected so
+// a prompt can never be used to reach outside the directory it was opened in.
+func validateEntryName(name string) error {
+	if name == "" {
+		return errors.New("name must not be empty")
+	}
+	if name == "." || name == ".." || name != filepath.Base(name) || strings.ContainsAny(name, "/\\") {
+		return fmt.Errorf("invalid name: %q (use a bare name without slashes)", name)
+	}
+	return nil
+}
+
+// noteFileName normalizes a typed note name: trims whitespace, appends the
+// default extension when none is given and enforces the allowedExts list.
+func noteFileName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if err := validateEntryName(name); err != nil {
+		return "", err
+	}
+	if filepath.Ext(name) == "" {
+		name += defaultNoteExt
+	}
+	if !allowedExts[strings.ToLower(filepath.Ext(name))] {
+		return "", fmt.Errorf("unsupported extension: %q (use .md or .txt)", filepath.Ext(name))
+	}
+	return name, nil
+}
+
+// safeTarget resolves dir/name against the notes root. The parent directory is
+// validated with safeJoinWithin (following symlinks) while the final element is
+// joined as-is, so operations act on the entry itself rather than a link target.
+// Returns the logical path (as shown in the tree) and the sanitized path to
+// use for the actual filesystem call.
+func safeTarget(dir, name string) (string, string, error) {
+	root, err := notesRoot()
+	if err != nil {
+		return "", "", err
+	}
+	rel, err := filepath.Rel(root, dir)
+	if err != nil {
+		return "", "", err
+	}
+	safeDir, err := safeJoinWithin(root, rel)
+	if err != nil {
+		return "", "", err
+	}
+	return filepath.Join(dir, name), filepath.Join(safeDir, name), nil
+}
+
+// safeEntry returns the sanitized path of an existing entry p, see safeTarget.
+func safeEntry(p string) (string, error) {
+	_, safe, err := safeTarget(filepath.Dir(p), filepath.Base(p))
+	return safe, err
+}
+
+// createNote creates a note called name inside dir and returns its path.
+// When tmpl is non-empty the named template is rendered into the new note
+// (see renderTemplate) with title filling {{title}}; otherwise the note starts
+// out empty, or with a "# title" heading when a title is given.
+func createNote(dir, name, title, tmpl string) (string, error) {
+	return createNoteAt(dir, name, title, tmpl, time.Now())
+}
+
+// createNoteAt is createNote with an explicit time for the {{date}} and
+// {{time}} placeholders (periodic notes use the start of their period).
+func createNoteAt(dir, name, title, tmpl string, now time.Time) (string, error) {
+	name, err := noteFileName(name)
+	if err != nil {
+		return "", err
+	}
+	body := ""
+	if tmpl != "" {
+		text, err := readTemplate(tmpl)
+		if err != nil {
+			return "", err
+		}
+		body = renderTemplate(text, templateVars(filepath.Join(dir, name), title, now))
+	} else if title != "" {
+		body = "# " + title + "\n"
+	}
+	return writeNewNote(dir, name, body)
+}
+
+// writeNewNote creates the note dir/name with the given body. The file is
+// created exclusively (O_EXCL) with 0600 permissions so an existing note is
+// never truncated and new notes are private to the user.
+func writeNewNote(dir, name, body string) (string, error) {
+	name, err := noteFileName(name)
+	if err != nil {
+		return "", err
+	}
+	logical, safe, err := safeTarget(dir, name)
+	if err != nil {
+		return "", err
+	}
+
+	// #nosec G304 -- safe is validated by safeJoinWithin against the notes root.
+	f, err := os.OpenFile(safe, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return "", fmt.Errorf("%s already exists", name)
+		}
+		return "", err
+	}
+	_, err = f.WriteString(body)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", err
+	}
+	return logical, nil
+}
+
+// existsError reports a destination that is already taken by another entry;
+// errors.Is(err, os.ErrExist) holds for it.
+type existsError string
+
+func (e existsError) Error() string { return string(e) + " already exists" }
+
+func (e existsError) Is(target error) bool { return target == os.ErrExist }
+
+// renameNoReplace renames src to dst but refuses to replace an existing dst.
+// os.Rename silently overwrites files, so regular files are hard-linked to
+// dst first (which fails atomically when dst exists) and then unlinked from
+// src. Directories, and filesystems without hard links, fall back to checking
+// dst before renaming; renaming a directory can at worst replace an empty
+// directory created in between, never a note.
+func renameNoReplace(src, dst string) error {
+	info, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+	if info.Mode().IsRegular() {
+		err := os.Link(src, dst)
+		switch {
+		case err == nil:
+			if err := os.Remove(src); err != nil {
+				_ = os.Remove(dst)
+				return err
+			}
+			return nil
+		case errors.Is(err, os.ErrExist):
+			return existsError(filepath.Base(dst))
+		case errors.Is(err, syscall.EXDEV) || errors.Is(err, os.ErrNotExist):
+			return err
+		}
+	}
+	if _, err := os.Lstat(dst); err == nil {
+		return existsError(filepath.Base(dst))
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return os.Rename(src, dst)
+}
+
+// makeDir creates the subdirectory name inside parent with 0700 permissions
+// and returns its path.
+func makeDir(parent, name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if err := validateEntryName(name); err != nil {
+		return "", err
+	}
+	logical, safe, err := safeTarget(parent, name)
+	if err != nil {
+		return "", err
+	}
+	if err := os.Mkdir(safe, 0o700); err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return "", fmt.Errorf("%s already exists", name)
+		}
+		return "", err
+	}
+	return logical, nil
+}
+
+// ensureDirs creates dir and any missing parents below the notes root
+// (0700, via makeDir) and returns one mkdir op per directory created, so the
+// whole chain can be undone together with whatever was moved into it.
+func ensureDirs(dir string) ([]fileOp, error) {
+	root, err := notesRoot()
+	if err != nil {
+		return nil, err
+	}
+	rel, err := filepath.Rel(root, dir)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return nil, fmt.Errorf("outside notes dir: %s", dir)
+	}
+	if rel == "." {
+		return nil, nil
+	}
+	var ops []fileOp
+	cur := root
+	for _, part := range strings.Split(rel, string(os.PathSeparator)) {
+		next := filepath.Join(cur, part)
+		safe, err := safeEntry(next)
+		if err != nil {
+			return ops, err
+		}
+		if info, err := os.Stat(safe); err == nil {
+			if !info.IsDir() {
+				return ops, fmt.Errorf("%s is not a directory", part)
+			}
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return ops, err
+		} else {
+			if _, err := makeDir(cur, part); err != nil {
+				return ops, err
+			}
+			ops = append(ops, fileOp{Kind: opMkdir, To: next})
+		}
+		cur = next
+	}
+	return ops, nil
+}
+
+// renameEntry renames the file or directory at p to newName within the same
+// parent directory and returns the new path. Both ends are validated against
+// the notes root and an existing entry is never overwritten.
+func renameEntry(p, newName string) (string, error) {
+	newName = strings.TrimSpace(newName)
+	if err := validateEntryName(newName); err != nil {
+		return "", err
+	}
+	src, err := safeEntry(p)
+	if err != nil {
+		return "", err
+	}
+	logical, dst, err := safeTarget(filepath.Dir(p), newName)
+	if err != nil {
+		return "", err
+	}
+	if err := renameNoReplace(src, dst); err != nil {
+		return "", err
+	}
+	return logical, nil
+}
+
+// removeEmptyDir deletes the directory at p, refusing when it still has entries.
+func removeEmptyDir(p string) error {
+	root, err := notesRoot()
+	if err != nil {
+		return err
+	}
+	if filepath.Clean(p) == filepath.Clean(root) {
+		return errors.New("refusing to remove the notes root")
+	}
+	safe, err := safeEntry(p)
+	if err != nil {
+		return err
+	}
+	ents, err := os.ReadDir(safe)
+	if err != nil {
+		return err
+	}
+	if len(ents) > 0 {
+		return fmt.Errorf("%s is not empty", filepath.Base(p))
+	}
+	return os.Remove(safe)
+}
+
+// moveEntry moves the file or directory at p into destDir, keeping its name,
+// and returns the new path. Source and destination must both stay inside the
+// notes root and an existing entry at the destination is never replaced.
+func moveEntry(p, destDir string) (string, error) {
+	if _, ok := safePathWithinNotes(p); !ok {
+		return "", fmt.Errorf("source outside notes dir: %s", p)
+	}
+	if _, ok := safePathWithinNotes(destDir); !ok || !isListableDir(destDir) {
+		return "", fmt.Errorf("invalid destination: %s", destDir)
+	}
+	if destDir == p || strings.HasPrefix(destDir, p+string(os.PathSeparator)) {
+		return "", errors.New("cannot move a directory into itself")
+	}
+	if filepath.Dir(p) == destDir {
+		return "", fmt.Errorf("%s is already there", filepath.Base(p))
+	}
+	src, err := safeEntry(p)
+	if err != nil {
+		return "", err
+	}
+	logical, dst, err := safeTarget(destDir, filepath.Base(p))
+	if err != nil {
+		return "", err
+	}
+	if err := renameNoReplace(src, dst); err != nil {
+		return "", err
+	}
+	return logical, nil
+}
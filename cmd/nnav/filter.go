@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// sizeRE matches sizes such as "512", "100k", "1.5M" or "2gb" (binary units).
+var sizeRE = regexp.MustCompile(`^(\d+(?:\.\d+)?)([kmg]?)b?$`)
+
+// noteFilter prunes notes by file metadata, using the FileInfo readDirNodes
+// already has (no note is read for it). Zero fields are unset.
+//   - since/before: modification time bounds (since inclusive).
+//   - larger/smaller: size bounds in bytes (exclusive), used when hasLarger/
+//     hasSmaller is set (so larger:0 hides empty notes).
+//   - spec: the filter as given, e.g. "since:7d larger:100k", for display.
+type noteFilter struct {
+	since      time.Time
+	before     time.Time
+	larger     int64
+	smaller    int64
+	hasLarger  bool
+	hasSmaller bool
+	spec       string
+}
+
+// newNoteFilter builds a filter from the values of --since, --before,
+// --larger-than and --smaller-than (empty strings are unset). Times are ages
+// as in `nnav archive` (7d, 2w, 6m, 1y) or dates (2006-01-02).
+func newNoteFilter(since, before, larger, smaller string, now time.Time) (noteFilter, error) {
+	var f noteFilter
+	var spec []string
+	var err error
+	if since != "" {
+		if f.since, err = parseWhen(since, now); err != nil {
+			return f, err
+		}
+		spec = append(spec, "since:"+since)
+	}
+	if before != "" {
+		if f.before, err = parseWhen(before, now); err != nil {
+			return f, err
+		}
+		spec = append(spec, "before:"+before)
+	}
+	if larger != "" {
+		if f.larger, err = parseSize(larger); err != nil {
+			return f, err
+		}
+		f.hasLarger = true
+		spec = append(spec, "larger:"+larger)
+	}
+	if smaller != "" {
+		if f.smaller, err = parseSize(smaller); err != nil {
+			return f, err
+		}
+		f.hasSmaller = true
+		spec = append(spec, "smaller:"+smaller)
+	}
+	f.spec = strings.Join(spec, " ")
+	return f, nil
+}
+
+// parseNoteFilter parses the TUI form of a filter: space-separated
+// key:value pairs with the keys since, before, larger and smaller (or
+// larger-than/smaller-than), e.g. "since:1w larger:10k".
+func parseNoteFilter(s string, now time.Time) (noteFilter, error) {
+	vals := map[string]string{}
+	for _, field := range strings.Fields(s) {
+		key, val, ok := strings.Cut(field, ":")
+		key = strings.TrimSuffix(strings.ToLower(key), "-than")
+		switch {
+		case !ok || val == "":
+			return noteFilter{}, fmt.Errorf("invalid filter %q (use e.g. since:7d before:2026-01-01 larger:100k smaller:1m)", field)
+		case key != "since" && key != "before" && key != "larger" && key != "smaller":
+			return noteFilter{}, fmt.Errorf("unknown filter %q (use since, before, larger or smaller)", key)
+		}
+		vals[key] = val
+	}
+	return newNoteFilter(vals["since"], vals["before"], vals["larger"], vals["smaller"], now)
+}
+
+// parseWhen parses an age (see ageCutoff) or a date (2006-01-02, local time).
+func parseWhen(s string, now time.Time) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", strings.TrimSpace(s), now.Location()); err == nil {
+		return t, nil
+	}
+	t, err := ageCutoff(s, now)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time: %q (use an age such as 7d, 2w, 6m, 1y or a date such as 2026-01-01)", s)
+	}
+	return t, nil
+}
+
+// parseSize parses a size such as "100k" or "1.5m" into bytes.
+func parseSize(s string) (int64, error) {
+	m := sizeRE.FindStringSubmatch(strings.ToLower(strings.TrimSpace(s)))
+	if m == nil {
+		return 0, fmt.Errorf("invalid size: %q (use e.g. 512, 100k, 2m or 1g)", s)
+	}
+	n, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size: %q", s)
+	}
+	switch m[2] {
+	case "k":
+		n *= 1 << 10
+	case "m":
+		n *= 1 << 20
+	case "g":
+		n *= 1 << 30
+	}
+	return int64(n), nil
+}
+
+// active reports whether the filter restricts anything.
+func (f noteFilter) active() bool {
+	return f.spec != ""
+}
+
+// keep reports whether a note with info passes the filter.
+func (f noteFilter) keep(info fs.FileInfo) bool {
+	mt := info.ModTime()
+	switch {
+	case !f.since.IsZero() && mt.Before(f.since):
+		return false
+	case !f.before.IsZero() && !mt.Before(f.before):
+		return false
+	case f.hasLarger && info.Size() <= f.larger:
+		return false
+	case f.hasSmaller && info.Size() >= f.smaller:
+		return false
+	}
+	return true
+}
+
+// openFilter prompts for the date and size filter (see parseNoteFilter);
+// an empty value removes it. The tree is rebuilt in the background, keeping
+// the active search.
+func (m *model) openFilter() {
+	m.ask("filter (since:7d before:2026-01-01 larger:100k smaller:1m): ", m.searchOpts.filter.spec, func(m *model, text string) tea.Cmd {
+		f, err := parseNoteFilter(text, time.Now())
+		if err != nil {
+			m.status = err.Error()
+			return nil
+		}
+		m.searchOpts.filter = f
+		m.status = "filter cleared"
+		if f.active() {
+			m.status = "filter: " + f.spec + " • <f> change • <esc> clear"
+		}
+		m.saveTreeState()
+		return m.startSearch(m.query.String())
+	})
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"io/fs"
+	"testing"
+	"time"
+)
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{"512", 512, false},
+		{"0", 0, false},
+		{"100k", 100 << 10, false},
+		{"100K", 100 << 10, false},
+		{"100kb", 100 << 10, false},
+		{"1.5m", 3 << 19, false},
+		{"2G", 2 << 30, false},
+		{" 10k ", 10 << 10, false},
+		{"12b", 12, false},
+		{"", 0, true},
+		{"k", 0, true},
+		{"1.k", 0, true},
+		{"-1k", 0, true},
+		{"10t", 0, true},
+		{"10 k", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseSize(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseSize(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseSize(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseWhen(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Time
+		wantErr bool
+	}{
+		{"7d", testNow.AddDate(0, 0, -7), false},
+		{"2w", testNow.AddDate(0, 0, -14), false},
+		{"6M", testNow.AddDate(0, -6, 0), false},
+		{"1y", testNow.AddDate(-1, 0, 0), false},
+		{"0d", testNow, false},
+		{"2026-01-01", time.Date(2026, 1, 1, 0, 0, 0, 0, time.Local), false},
+		{" 2026-01-01 ", time.Date(2026, 1, 1, 0, 0, 0, 0, time.Local), false},
+		{"2026-02-30", time.Time{}, true},
+		{"2026/01/01", time.Time{}, true},
+		{"yesterday", time.Time{}, true},
+		{"7", time.Time{}, true},
+		{"", time.Time{}, true},
+	}
+	for _, tt := range tests {
+		got, err := parseWhen(tt.in, testNow)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseWhen(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("parseWhen(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+// fakeInfo is an fs.FileInfo with just a size and a modification time.
+type fakeInfo struct {
+	size int64
+	mod  time.Time
+}
+
+func (f fakeInfo) Name() string       { return "note.md" }
+func (f fakeInfo) Size() int64        { return f.size }
+func (f fakeInfo) Mode() fs.FileMode  { return 0o600 }
+func (f fakeInfo) ModTime() time.Time { return f.mod }
+func (f fakeInfo) IsDir() bool        { return false }
+func (f fakeInfo) Sys() any           { return nil }
+
+func TestNoteFilter(t *testing.T) {
+	old := fakeInfo{size: 50 << 10, mod: testNow.AddDate(0, 0, -30)}
+	recent := fakeInfo{size: 200 << 10, mod: testNow.AddDate(0, 0, -1)}
+	empty := fakeInfo{mod: testNow}
+	tests := []struct {
+		spec       string
+		old, fresh bool // whether each note is kept
+		empty      bool
+		wantErr    bool
+	}{
+		{"", true, true, true, false},
+		{"since:7d", false, true, true, false},
+		{"before:1w", true, false, false, false},
+		{"larger:100k", false, true, false, false},
+		{"larger:0", true, true, false, false}, // 0 is a bound, not "unset"
+		{"larger-than:0b", true, true, false, false},
+		{"smaller-than:100k", true, false, true, false},
+		{"since:2026-09-01 larger:10k", true, true, false, false},
+		{"since:1d", false, true, true, false}, // since is inclusive
+		{"since:7d smaller:100k", false, false, true, false},
+		{"since", false, false, false, true},
+		{"since:", false, false, false, true},
+		{"newer:7d", false, false, false, true},
+		{"larger:lots", false, false, false, true},
+	}
+	for _, tt := range tests {
+		f, err := parseNoteFilter(tt.spec, testNow)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseNoteFilter(%q) error = %v, want error %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if f.active() != (tt.spec != "") {
+			t.Errorf("parseNoteFilter(%q).active() = %v", tt.spec, f.active())
+		}
+		if got := f.keep(old); got != tt.old {
+			t.Errorf("%q keeps the old note: %v, want %v", tt.spec, got, tt.old)
+		}
+		if got := f.keep(recent); got != tt.fresh {
+			t.Errorf("%q keeps the recent note: %v, want %v", tt.spec, got, tt.fresh)
+		}
+		if got := f.keep(empty); got != tt.empty {
+			t.Errorf("%q keeps the empty note: %v, want %v", tt.spec, got, tt.empty)
+		}
+	}
+}
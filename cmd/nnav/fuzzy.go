@@ -0,0 +1,250 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// finderHelp is the footer shown while a finder is open.
+const finderHelp = "type to filter • ↑/↓ move • <enter> choose • <esc> cancel"
+
+// Fuzzy scoring weights, loosely modelled on fzf: every matched character
+// scores, matches at word boundaries and runs of consecutive matches score
+// extra (a run keeps the bonus of its first character, so "note" ranks
+// note.md above n-o-t-e.md), and gaps between matches cost a little.
+const (
+	fuzzyMatchScore  = 16
+	fuzzyBoundary    = 8
+	fuzzyCamel       = 7
+	fuzzyConsecutive = 4
+	fuzzyGapStart    = -3
+	fuzzyGapExtend   = -1
+)
+
+// fuzzyHit is one item matching a fuzzy query.
+//   - Index: position of the item in the searched list.
+//   - Score: higher is better.
+//   - Pos: rune offsets of the matched characters (for highlighting).
+type fuzzyHit struct {
+	Index int
+	Score int
+	Pos   []int
+}
+
+// fuzzyMatch reports whether all runes of pattern occur in s in order
+// (case-insensitive) and scores the match. Like fzf's v1 algorithm, the
+// earliest complete match is found first and then shortened from its end,
+// which prefers tight matches without trying every alignment.
+func fuzzyMatch(pattern, s string) (int, []int, bool) {
+	pat := []rune(strings.ToLower(pattern))
+	if len(pat) == 0 {
+		return 0, nil, true
+	}
+	orig := []rune(s)
+	text := []rune(strings.ToLower(s))
+
+	// Forward: earliest end of a match.
+	pi, end := 0, -1
+	for i, r := range text {
+		if r == pat[pi] {
+			pi++
+			if pi == len(pat) {
+				end = i
+				break
+			}
+		}
+	}
+	if end < 0 {
+		return 0, nil, false
+	}
+	// Backward: latest start that still matches up to end.
+	pos := make([]int, len(pat))
+	pi = len(pat) - 1
+	for i := end; i >= 0 && pi >= 0; i-- {
+		if text[i] == pat[pi] {
+			pos[pi] = i
+			pi--
+		}
+	}
+
+	score, run := 0, 0 // run: bonus of the first character of the current run
+	for k, i := range pos {
+		bonus := 0
+		switch {
+		case i == 0 || strings.ContainsRune("/-_ .", orig[i-1]):
+			bonus = fuzzyBoundary
+		case unicode.IsUpper(orig[i]) && unicode.IsLower(orig[i-1]):
+			bonus = fuzzyCamel
+		}
+		if k > 0 && i == pos[k-1]+1 {
+			bonus = max(bonus, max(run, fuzzyConsecutive))
+		} else {
+			if k > 0 {
+				score += fuzzyGapStart + fuzzyGapExtend*(i-pos[k-1]-2)
+			}
+			run = bonus
+		}
+		score += fuzzyMatchScore + bonus
+	}
+	return score, pos, true
+}
+
+// fuzzyFilter returns the items matching query, best first; ties go to the
+// shorter item, then to list order. An empty query keeps every item in order.
+func fuzzyFilter(query string, items []string) []fuzzyHit {
+	hits := make([]fuzzyHit, 0, len(items))
+	for i, it := range items {
+		if score, pos, ok := fuzzyMatch(query, it); ok {
+			hits = append(hits, fuzzyHit{Index: i, Score: score, Pos: pos})
+		}
+	}
+	if query == "" {
+		return hits
+	}
+	sort.SliceStable(hits, func(a, b int) bool {
+		ha, hb := hits[a], hits[b]
+		if ha.Score != hb.Score {
+			return ha.Score > hb.Score
+		}
+		return len(items[ha.Index]) < len(items[hb.Index])
+	})
+	return hits
+}
+
+// finder is a modal list filtered by a fuzzy query typed by the user
+// (e.g. choosing the directory to file an inbox note into).
+//   - title: heading describing the choice.
+//   - items: labels that are matched and shown, matched characters highlighted.
+//   - hits: items matching query, best first.
+//   - onPick: called with the index (into items) of the chosen entry.
+//   - onAlt: optional second action on <tab> (e.g. reveal instead of open).
+//   - loading: items are still being collected (see notesListedMsg).
+type finder struct {
+	title   string
+	items   []string
+	query   string
+	hits    []fuzzyHit
+	cursor  int
+	scroll  int
+	onPick  func(m *model, i int) tea.Cmd
+	onAlt   func(m *model, i int) tea.Cmd
+	loading bool
+}
+
+// openFinder opens a finder over items.
+func (m *model) openFinder(title string, items []string, onPick func(m *model, i int) tea.Cmd) {
+	m.finder = &finder{title: title, items: items, onPick: onPick}
+	m.finder.filter()
+	m.status = finderHelp
+}
+
+// setItems replaces the finder's items (once loaded) and re-runs the query.
+func (f *finder) setItems(items []string) {
+	f.items, f.loading = items, false
+	f.filter()
+}
+
+// filter re-runs the query and moves the cursor back to the best hit.
+func (f *finder) filter() {
+	f.hits = fuzzyFilter(f.query, f.items)
+	f.cursor, f.scroll = 0, 0
+}
+
+// updateFinder handles keys while a finder is open. Printable keys edit the
+// query, so the list is navigated with the arrow keys or ctrl+n / ctrl+p.
+func (m model) updateFinder(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	f := m.finder
+	switch msg.Type {
+	case tea.KeyEsc, tea.KeyCtrlC:
+		m.finder = nil
+		m.status = "cancelled"
+		return m, nil
+
+	case tea.KeyEnter:
+		if len(f.hits) == 0 {
+			return m, nil
+		}
+		m.finder = nil
+		m.status = helpText
+		return m, f.onPick(&m, f.hits[f.cursor].Index)
+
+	case tea.KeyTab:
+		if f.onAlt == nil || len(f.hits) == 0 {
+			return m, nil
+		}
+		m.finder = nil
+		m.status = helpText
+		return m, f.onAlt(&m, f.hits[f.cursor].Index)
+
+	case tea.KeyDown, tea.KeyCtrlN:
+		if f.cursor < len(f.hits)-1 {
+			f.cursor++
+		}
+
+	case tea.KeyUp, tea.KeyCtrlP:
+		if f.cursor > 0 {
+			f.cursor--
+		}
+
+	case tea.KeyBackspace:
+		if r := []rune(f.query); len(r) > 0 {
+			f.query = string(r[:len(r)-1])
+			f.filter()
+		}
+
+	case tea.KeyCtrlU:
+		f.query = ""
+		f.filter()
+
+	case tea.KeyRunes, tea.KeySpace:
+		f.query += string(msg.Runes)
+		f.filter()
+	}
+	f.scroll = scrollWindow(f.cursor, f.scroll, len(f.hits), m.height-1)
+	return m, nil
+}
+
+// view renders the finder: the query below the title, then the hits with
+// the matched characters highlighted.
+func (f *finder) view(m model) string {
+	title := f.title + "\n> " + f.query + "█"
+	if f.loading {
+		title += "  (loading…)"
+	}
+	return m.frame(title, len(f.hits), func(i int) string {
+		h := f.hits[i]
+		return "• " + highlightRunes(f.items[h.Index], h.Pos, i == f.cursor)
+	}, f.cursor, f.scroll)
+}
+
+// highlightRunes renders s with the runes at pos emphasized. On the cursor
+// row every segment carries the reverse attribute itself, since the reset
+// after a styled segment would otherwise end the row's reverse video early.
+func highlightRunes(s string, pos []int, cursor bool) string {
+	if len(pos) == 0 {
+		return s
+	}
+	base := lipgloss.NewStyle().Reverse(cursor)
+	hl := base.Bold(true).Foreground(lipgloss.Color("5"))
+	var b strings.Builder
+	rs := []rune(s)
+	next, start := 0, 0
+	for i := range rs {
+		if next < len(pos) && pos[next] == i {
+			if start < i {
+				b.WriteString(base.Render(string(rs[start:i])))
+			}
+			b.WriteString(hl.Render(string(rs[i])))
+			start = i + 1
+			next++
+		}
+	}
+	if start < len(rs) {
+		b.WriteString(base.Render(string(rs[start:])))
+	}
+	return b.String()
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFuzzyMatch(t *testing.T) {
+	tests := []struct {
+		pattern, s string
+		ok         bool
+		pos        []int
+	}{
+		{"", "anything", true, nil},
+		{"abc", "abc", true, []int{0, 1, 2}},
+		{"ABC", "xaXbXc", true, []int{1, 3, 5}},
+		{"abc", "acb", false, nil},
+		{"abc", "ab", false, nil},
+		{"mtg", "notes/meeting.md", true, []int{6, 9, 12}},
+		{"ab", "a_a_b", true, []int{2, 4}},           // shortened from the end
+		{"nts", "notes/ts.md", true, []int{0, 2, 4}}, // earliest match, not the best one
+		{"é", "Café", true, []int{3}},
+		{"ist", "İstanbul", true, []int{0, 1, 2}},
+		{"l", "İstanbul", true, []int{7}},
+	}
+	for _, tt := range tests {
+		_, pos, ok := fuzzyMatch(tt.pattern, tt.s)
+		if ok != tt.ok {
+			t.Errorf("fuzzyMatch(%q, %q) ok = %v, want %v", tt.pattern, tt.s, ok, tt.ok)
+			continue
+		}
+		if ok && !reflect.DeepEqual(pos, tt.pos) {
+			t.Errorf("fuzzyMatch(%q, %q) pos = %v, want %v", tt.pattern, tt.s, pos, tt.pos)
+		}
+	}
+}
+
+func TestFuzzyMatchScore(t *testing.T) {
+	// Each pair: the pattern should score higher against better than worse.
+	tests := []struct {
+		pattern, better, worse string
+	}{
+		{"note", "note.md", "n-o-t-e.md"},           // consecutive
+		{"mn", "meeting-notes.md", "memento.md"},    // word boundary
+		{"fb", "fooBar.md", "foobar.md"},            // camel case
+		{"ab", "a-b", "a---b"},                      // shorter gap
+		{"jr", "journal/report.md", "jxxxxxxxr.md"}, // boundaries beat gaps
+	}
+	for _, tt := range tests {
+		b, _, okB := fuzzyMatch(tt.pattern, tt.better)
+		w, _, okW := fuzzyMatch(tt.pattern, tt.worse)
+		if !okB || !okW || b <= w {
+			t.Errorf("%q: %q scores %d, %q scores %d", tt.pattern, tt.better, b, tt.worse, w)
+		}
+	}
+}
+
+func TestFuzzyFilter(t *testing.T) {
+	items := []string{"inbox.md", "projects/notes.md", "notes.md", "misc/no-test.txt", "ideas.md"}
+	var got []string
+	for _, h := range fuzzyFilter("notes", items) {
+		got = append(got, items[h.Index])
+	}
+	want := []string{"notes.md", "projects/notes.md", "misc/no-test.txt"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("fuzzyFilter(notes) = %v, want %v", got, want)
+	}
+	if hits := fuzzyFilter("", items); len(hits) != len(items) || hits[2].Index != 2 {
+		t.Errorf("fuzzyFilter(\"\") = %v, want every item in order", hits)
+	}
+}
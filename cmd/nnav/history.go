@@ -0,0 +1,336 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// historyFile stores the undo/redo log in the user's home dir, next to ~/.nnav,
+// so operations can still be undone after nnav restarts.
+const historyFile = ".nnav-history"
+
+// historyLimit caps the number of undoable changes kept on disk.
+const historyLimit = 200
+
+// opKind names a reversible file operation.
+type opKind string
+
+const (
+	opCreate  opKind = "create"  // note created at To
+	opRestore opKind = "restore" // trashed note restored to To
+	opMkdir   opKind = "mkdir"   // directory created at To
+	opRmdir   opKind = "rmdir"   // empty directory removed at From
+	opRename  opKind = "rename"  // entry renamed From → To (same directory)
+	opMove    opKind = "move"    // entry moved From → To (other directory)
+	opTrash   opKind = "trash"   // note at From moved to the trash as Trash
+	opEdit    opKind = "edit"    // note at To rewritten Before → After (e.g. frontmatter)
+)
+
+// fileOp is one logged operation with enough information to reverse it.
+//   - From/To: logical paths before and after the operation.
+//   - Trash: file name inside the trash for notes that currently live there
+//     (set by trash, and by undoing a create or restore).
+//   - Before/After: full note contents around a content edit.
+//   - Dead: the trashed note the op refers to was purged, so the op can no
+//     longer be replayed and is skipped (see markPurged).
+type fileOp struct {
+	Kind   opKind `json:"kind"`
+	From   string `json:"from,omitempty"`
+	To     string `json:"to,omitempty"`
+	Trash  string `json:"trash,omitempty"`
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+	Dead   bool   `json:"dead,omitempty"`
+}
+
+// String describes the op for messages, e.g. "trash a.md" or "move a.md → b".
+func (op fileOp) String() string {
+	switch {
+	case op.From != "" && op.To != "":
+		return string(op.Kind) + " " + filepath.Base(op.From) + " → " + filepath.Base(op.To)
+	case op.From != "":
+		return string(op.Kind) + " " + filepath.Base(op.From)
+	}
+	return string(op.Kind) + " " + filepath.Base(op.To)
+}
+
+// change is one user action as shown in messages; it may consist of several
+// ops (e.g. a merge creates one note and trashes others) undone as a unit.
+type change struct {
+	Label string    `json:"label"`
+	Time  time.Time `json:"time"`
+	Ops   []fileOp  `json:"ops"`
+}
+
+// history is the persisted undo/redo log. Both stacks grow at the end.
+type history struct {
+	Undo []change `json:"undo"`
+	Redo []change `json:"redo"`
+}
+
+// historyPath returns the location of the state file (~/.nnav-history).
+func historyPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, historyFile), nil
+}
+
+// loadHistory reads the log; a missing file yields an empty history.
+func loadHistory() (*history, error) {
+	p, err := historyPath()
+	if err != nil {
+		return nil, err
+	}
+	h := &history{}
+	// #nosec G304 -- p is derived from $HOME and not attacker-controlled.
+	data, err := os.ReadFile(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, h); err != nil {
+		return nil, fmt.Errorf("corrupt %s: %w", historyFile, err)
+	}
+	return h, nil
+}
+
+// save writes the log atomically with 0600 permissions, trimming old entries.
+func (h *history) save() error {
+	p, err := historyPath()
+	if err != nil {
+		return err
+	}
+	if n := len(h.Undo); n > historyLimit {
+		h.Undo = h.Undo[n-historyLimit:]
+	}
+	if n := len(h.Redo); n > historyLimit {
+		h.Redo = h.Redo[n-historyLimit:]
+	}
+	data, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(p, data, 0o600)
+}
+
+// recordChange appends a completed change to the undo stack and clears the
+// redo stack, like any editor does after a fresh action.
+func recordChange(label string, ops ...fileOp) error {
+	h, err := loadHistory()
+	if err != nil {
+		return err
+	}
+	h.Undo = append(h.Undo, change{Label: label, Time: time.Now(), Ops: ops})
+	h.Redo = nil
+	return h.save()
+}
+
+// undoLast reverses the most recent change and moves it to the redo stack.
+// Returns the change and a path worth selecting afterwards.
+func undoLast() (change, string, error) {
+	return replay(true)
+}
+
+// redoLast re-applies the most recently undone change.
+func redoLast() (change, string, error) {
+	return replay(false)
+}
+
+// replay pops a change from the undo (or redo) stack, applies its ops in the
+// appropriate order and pushes it onto the opposite stack. Dead ops are
+// skipped and named in the returned error. When an op fails, the ops of the
+// change that already ran are rolled back and the change is dropped from the
+// history, so older changes are not stuck behind it; the error says so and
+// lists anything the rollback could not restore. The returned change has a
+// label whenever files may have changed.
+func replay(undo bool) (change, string, error) {
+	h, err := loadHistory()
+	if err != nil {
+		return change{}, "", err
+	}
+	from, to := &h.Undo, &h.Redo
+	verb := "undo"
+	if !undo {
+		from, to = &h.Redo, &h.Undo
+		verb = "redo"
+	}
+	if len(*from) == 0 {
+		return change{}, "", fmt.Errorf("nothing to %s", verb)
+	}
+	c := (*from)[len(*from)-1]
+	*from = (*from)[:len(*from)-1]
+
+	focus := ""
+	var done []int // ops applied so far, in order
+	var skipped []string
+	for i := range c.Ops {
+		j := i
+		if undo {
+			j = len(c.Ops) - 1 - i // reverse order when undoing
+		}
+		if c.Ops[j].Dead {
+			skipped = append(skipped, c.Ops[j].String())
+			continue
+		}
+		f, err := applyOp(&c.Ops[j], undo)
+		if err != nil {
+			msg := fmt.Sprintf("%s %q failed: %v; dropped it from the history", verb, c.Label, err)
+			if stuck := rollback(c.Ops, done, undo); len(stuck) > 0 {
+				msg += "; could not roll back: " + strings.Join(stuck, ", ")
+			}
+			if err := h.save(); err != nil {
+				msg += " (undo log: " + err.Error() + ")"
+			}
+			return c, focus, errors.New(msg)
+		}
+		done = append(done, j)
+		if focus == "" {
+			focus = f
+		}
+	}
+
+	if len(done) > 0 {
+		*to = append(*to, c)
+	}
+	if err := h.save(); err != nil {
+		return c, focus, fmt.Errorf("%s %q: undo log: %w", verb, c.Label, err)
+	}
+	if len(skipped) > 0 {
+		return c, focus, fmt.Errorf("%s %q: skipped %s (purged from the trash)", verb, c.Label, strings.Join(skipped, ", "))
+	}
+	return c, focus, nil
+}
+
+// rollback reverses the ops of a failed replay (the indices in done, in the
+// order they ran) and returns the ones it could not reverse.
+func rollback(ops []fileOp, done []int, undo bool) []string {
+	var stuck []string
+	for i := len(done) - 1; i >= 0; i-- {
+		op := &ops[done[i]]
+		if _, err := applyOp(op, !undo); err != nil {
+			stuck = append(stuck, op.String()+": "+err.Error())
+		}
+	}
+	return stuck
+}
+
+// markPurged marks every logged op that refers to the trashed note name as
+// dead, so undo and redo skip it instead of failing on it forever.
+func markPurged(name string) error {
+	h, err := loadHistory()
+	if err != nil {
+		return err
+	}
+	found := false
+	for _, stack := range [][]change{h.Undo, h.Redo} {
+		for i := range stack {
+			for j := range stack[i].Ops {
+				if op := &stack[i].Ops[j]; op.Trash == name && !op.Dead {
+					op.Dead, found = true, true
+				}
+			}
+		}
+	}
+	if !found {
+		return nil
+	}
+	return h.save()
+}
+
+// applyOp reverses (undo=true) or re-applies op and returns the path that the
+// cursor should land on. Ops that move notes into the trash update op.Trash so
+// the matching inverse can find them again.
+func applyOp(op *fileOp, undo bool) (string, error) {
+	switch op.Kind {
+	case opCreate, opRestore:
+		if undo {
+			e, err := trashNote(op.To)
+			if err != nil {
+				return "", err
+			}
+			op.Trash = e.Name
+			return filepath.Dir(op.To), nil
+		}
+		return restoreTrash(trashEntry{Name: op.Trash, OrigPath: op.To})
+
+	case opTrash:
+		if undo {
+			return restoreTrash(trashEntry{Name: op.Trash, OrigPath: op.From})
+		}
+		e, err := trashNote(op.From)
+		if err != nil {
+			return "", err
+		}
+		op.Trash = e.Name
+		return filepath.Dir(op.From), nil
+
+	case opMkdir, opRmdir:
+		p := op.To
+		if op.Kind == opRmdir {
+			p = op.From
+		}
+		// Undoing a mkdir and redoing an rmdir both remove the directory.
+		if undo == (op.Kind == opMkdir) {
+			return filepath.Dir(p), removeEmptyDir(p)
+		}
+		return makeDir(filepath.Dir(p), filepath.Base(p))
+
+	case opRename, opMove:
+		if undo {
+			return relocate(op.To, op.From)
+		}
+		return relocate(op.From, op.To)
+
+	case opEdit:
+		if undo {
+			return op.To, rewriteNote(op.To, op.After, op.Before)
+		}
+		return op.To, rewriteNote(op.To, op.Before, op.After)
+	}
+	return "", fmt.Errorf("unknown operation %q", op.Kind)
+}
+
+// relocate renames from → to for undo/redo, with the same safety checks as
+// the interactive rename/move: both ends inside the notes root, no overwrite.
+func relocate(from, to string) (string, error) {
+	src, err := safeEntry(from)
+	if err != nil {
+		return "", err
+	}
+	logical, dst, err := safeTarget(filepath.Dir(to), filepath.Base(to))
+	if err != nil {
+		return "", err
+	}
+	return logical, renameNoReplace(src, dst)
+}
+
+// rewriteNote replaces the contents of p with want, but only if the note
+// still holds expect; edits made since the logged change are never clobbered.
+func rewriteNote(p, expect, want string) error {
+	safe, err := safeEntry(p)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(safe)
+	if err != nil {
+		return err
+	}
+	// #nosec G304 -- safe is validated against the notes root by safeEntry.
+	cur, err := os.ReadFile(safe)
+	if err != nil {
+		return err
+	}
+	if string(cur) != expect {
+		return fmt.Errorf("%s was modified since", filepath.Base(p))
+	}
+	return writeFileAtomic(safe, []byte(want), info.Mode().Perm())
+}
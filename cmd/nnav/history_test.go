@@ -0,0 +1,211 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// readTestNote returns the contents of p, or "<missing>".
+func readTestNote(t *testing.T, p string) string {
+	t.Helper()
+	data, err := os.ReadFile(p)
+	if os.IsNotExist(err) {
+		return "<missing>"
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// stackSizes returns the number of undo and redo entries on disk.
+func stackSizes(t *testing.T) (int, int) {
+	t.Helper()
+	h, err := loadHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(h.Undo), len(h.Redo)
+}
+
+func TestReplayUndoRedo(t *testing.T) {
+	root := testNotes(t, map[string]string{"b.md": "v2", "c.md": "hello"})
+	a, b, c := filepath.Join(root, "a.md"), filepath.Join(root, "b.md"), filepath.Join(root, "c.md")
+	e, err := trashNote(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// As if a.md (holding v1) had been edited and renamed, and c.md trashed.
+	if err := recordChange("edit and rename a.md",
+		fileOp{Kind: opEdit, To: a, Before: "v1", After: "v2"},
+		fileOp{Kind: opRename, From: a, To: b},
+	); err != nil {
+		t.Fatal(err)
+	}
+	if err := recordChange("trash c.md", fileOp{Kind: opTrash, From: c, Trash: e.Name}); err != nil {
+		t.Fatal(err)
+	}
+
+	if ch, _, err := undoLast(); err != nil || ch.Label != "trash c.md" {
+		t.Fatalf("undo = %q, %v", ch.Label, err)
+	}
+	if got := readTestNote(t, c); got != "hello" {
+		t.Errorf("c.md after undoing its trash = %q", got)
+	}
+	if ch, focus, err := undoLast(); err != nil || ch.Label != "edit and rename a.md" || focus != a {
+		t.Fatalf("undo = %q, focus %s, %v", ch.Label, focus, err)
+	}
+	if got := readTestNote(t, a); got != "v1" || readTestNote(t, b) != "<missing>" {
+		t.Errorf("after undo: a.md = %q, b.md = %q", got, readTestNote(t, b))
+	}
+	if _, _, err := undoLast(); err == nil || err.Error() != "nothing to undo" {
+		t.Errorf("undo on an empty history: %v", err)
+	}
+	if u, r := stackSizes(t); u != 0 || r != 2 {
+		t.Errorf("stacks = %d undo, %d redo; want 0, 2", u, r)
+	}
+
+	if _, _, err := redoLast(); err != nil {
+		t.Fatal(err)
+	}
+	if got := readTestNote(t, b); got != "v2" || readTestNote(t, a) != "<missing>" {
+		t.Errorf("after redo: b.md = %q, a.md = %q", got, readTestNote(t, a))
+	}
+	if _, _, err := redoLast(); err != nil {
+		t.Fatal(err)
+	}
+	if readTestNote(t, c) != "<missing>" {
+		t.Error("redo did not trash c.md again")
+	}
+	// The redo trashed c.md under a new name, which undo must find.
+	if _, _, err := undoLast(); err != nil || readTestNote(t, c) != "hello" {
+		t.Errorf("undo after redo: c.md = %q, %v", readTestNote(t, c), err)
+	}
+
+	if err := recordChange("new", fileOp{Kind: opCreate, To: a}); err != nil {
+		t.Fatal(err)
+	}
+	if _, r := stackSizes(t); r != 0 {
+		t.Errorf("a new change kept %d redo entries", r)
+	}
+}
+
+func TestReplayRollsBackFailedChange(t *testing.T) {
+	root := testNotes(t, map[string]string{"a.md": "after", "keep.md": "new"})
+	a, keep := filepath.Join(root, "a.md"), filepath.Join(root, "keep.md")
+	if err := recordChange("older", fileOp{Kind: opEdit, To: keep, Before: "old", After: "new"}); err != nil {
+		t.Fatal(err)
+	}
+	// Undone in reverse: the edit of a.md works, then moving the missing
+	// gone.md back fails, so the edit has to be rolled back.
+	if err := recordChange("broken",
+		fileOp{Kind: opRename, From: filepath.Join(root, "was.md"), To: filepath.Join(root, "gone.md")},
+		fileOp{Kind: opEdit, To: a, Before: "before", After: "after"},
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	ch, _, err := undoLast()
+	if err == nil || !strings.Contains(err.Error(), `undo "broken" failed`) || !strings.Contains(err.Error(), "dropped it from the history") {
+		t.Fatalf("undo error = %v", err)
+	}
+	if strings.Contains(err.Error(), "could not roll back") {
+		t.Errorf("rollback failed: %v", err)
+	}
+	if ch.Label != "broken" {
+		t.Errorf("undo returned %q", ch.Label)
+	}
+	if got := readTestNote(t, a); got != "after" {
+		t.Errorf("a.md = %q, want the edit rolled back", got)
+	}
+	if u, r := stackSizes(t); u != 1 || r != 0 {
+		t.Errorf("stacks = %d undo, %d redo; want 1, 0", u, r)
+	}
+
+	// The older change is no longer stuck behind the failed one.
+	if ch, _, err := undoLast(); err != nil || ch.Label != "older" || readTestNote(t, keep) != "old" {
+		t.Errorf("undo = %q, %v; keep.md = %q", ch.Label, err, readTestNote(t, keep))
+	}
+}
+
+func TestReplaySkipsPurgedNotes(t *testing.T) {
+	root := testNotes(t, map[string]string{"a.md": "merged", "x.md": "x", "y.md": "y"})
+	a := filepath.Join(root, "a.md")
+	x, y := filepath.Join(root, "x.md"), filepath.Join(root, "y.md")
+	ex, err := trashNote(x)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ey, err := trashNote(y)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := recordChange("trash y.md", fileOp{Kind: opTrash, From: y, Trash: ey.Name}); err != nil {
+		t.Fatal(err)
+	}
+	if err := recordChange("merge",
+		fileOp{Kind: opCreate, To: a},
+		fileOp{Kind: opTrash, From: x, Trash: ex.Name},
+	); err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range []trashEntry{ex, ey} {
+		if err := purgeTrash(e); err != nil {
+			t.Fatal(err)
+		}
+		if err := markPurged(e.Name); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Part of the change still works: a.md goes to the trash, x.md is skipped.
+	_, _, err = undoLast()
+	if err == nil || err.Error() != `undo "merge": skipped trash x.md (purged from the trash)` {
+		t.Errorf("undo error = %v", err)
+	}
+	if readTestNote(t, a) != "<missing>" {
+		t.Error("a.md was not trashed")
+	}
+	if u, r := stackSizes(t); u != 1 || r != 1 {
+		t.Errorf("stacks = %d undo, %d redo; want 1, 1", u, r)
+	}
+
+	// Nothing of this change can run any more, so it is dropped.
+	_, _, err = undoLast()
+	if err == nil || !strings.Contains(err.Error(), "skipped trash y.md") {
+		t.Errorf("undo error = %v", err)
+	}
+	if u, r := stackSizes(t); u != 0 || r != 1 {
+		t.Errorf("stacks = %d undo, %d redo; want 0, 1", u, r)
+	}
+
+	// Redo of the merge re-creates a.md; the dead op stays skipped.
+	if _, _, err := redoLast(); err == nil || !strings.Contains(err.Error(), "skipped trash x.md") {
+		t.Errorf("redo error = %v", err)
+	}
+	if got := readTestNote(t, a); got != "merged" {
+		t.Errorf("a.md after redo = %q", got)
+	}
+}
+
+func TestMarkPurged(t *testing.T) {
+	testNotes(t, nil)
+	if err := recordChange("one", fileOp{Kind: opTrash, From: "/n/a.md", Trash: "a.md"}, fileOp{Kind: opTrash, From: "/n/b.md", Trash: "b.md"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := markPurged("a.md"); err != nil {
+		t.Fatal(err)
+	}
+	if err := markPurged("unknown.md"); err != nil {
+		t.Fatal(err)
+	}
+	h, err := loadHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ops := h.Undo[0].Ops; !ops[0].Dead || ops[1].Dead {
+		t.Errorf("dead = %v, %v; want true, false", ops[0].Dead, ops[1].Dead)
+	}
+}
@@ -0,0 +1,473 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// indexVersion is bumped whenever the on-disk format or the tokenizer
+// changes; an index written by another version is rebuilt from scratch.
+const indexVersion = 1
+
+// noteIndex is the persistent full-text index: an inverted index from the
+// lowercased words of every note to the notes containing them, plus each
+// note's title, so unchanged notes need not be read to build the tree or to
+// rule them out of a search.
+//
+// An index is never modified once published (see currentIndex): a refresh
+// builds the next one from it, so searches running in the background keep a
+// consistent snapshot.
+//   - Root: notes dir the index belongs to.
+//   - Docs: one entry per note, by ID; removed notes leave an empty slot.
+//   - Postings: word → IDs of the notes containing it.
+//   - byPath: ID of every live note (not persisted).
+type noteIndex struct {
+	Version  int
+	Root     string
+	Docs     []indexDoc
+	Postings map[string][]int32
+	byPath   map[string]int32
+}
+
+// indexDoc is what the index remembers about one note.
+//   - Path: full path ("" for a removed note).
+//   - ModTime/Size: the file's state when it was indexed; the entry is only
+//     trusted while both still match.
+//   - Title: first Markdown heading, as found by scanNote.
+type indexDoc struct {
+	Path    string
+	ModTime int64
+	Size    int64
+	Title   string
+}
+
+// indexStats summarizes a refresh for `nnav index`.
+type indexStats struct {
+	Notes   int
+	Updated int
+	Removed int
+}
+
+var (
+	// indexMu serializes refreshes; readers use currentIndex without locking.
+	indexMu sync.Mutex
+	// indexCur is the published index (nil until loaded by refreshIndex).
+	indexCur atomic.Pointer[noteIndex]
+)
+
+// currentIndex returns the published index, or nil when nnav runs without
+// one (subcommands, or when it could not be loaded).
+func currentIndex() *noteIndex {
+	return indexCur.Load()
+}
+
+// indexPath returns the index file for the notes dir root, in the user cache
+// dir (e.g. ~/.cache/nnav on Linux). Every notes dir gets its own file.
+func indexPath(root string) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(root))
+	return filepath.Join(dir, "nnav", "index-"+hex.EncodeToString(sum[:8])+".gob"), nil
+}
+
+// refreshIndex brings the index of root up to date and publishes it: the
+// saved index is loaded on first use, notes whose mtime or size changed (and
+// new ones) are re-read, removed notes are dropped, and the result is saved
+// when anything changed. With rebuild set, the saved index is ignored.
+func refreshIndex(root string, rebuild bool) (indexStats, error) {
+	indexMu.Lock()
+	defer indexMu.Unlock()
+
+	old := currentIndex()
+	if old == nil || old.Root != root || rebuild {
+		old = nil
+		if !rebuild {
+			old, _ = loadIndex(root) // a missing or unreadable index is rebuilt
+		}
+	}
+	ix, stats, err := updateIndex(old, root)
+	if err != nil {
+		return stats, err
+	}
+	indexCur.Store(ix)
+	if ix == old && !rebuild {
+		return stats, nil
+	}
+	return stats, ix.save()
+}
+
+// publishSavedIndex publishes the saved index of root, if there is one,
+// without checking it against the notes: lookup only trusts entries whose
+// mtime and size still match, so a stale index is safe to use until the
+// background refresh (see refreshIndexCmd) replaces it.
+func publishSavedIndex(root string) {
+	if ix, err := loadIndex(root); err == nil {
+		indexCur.CompareAndSwap(nil, ix)
+	}
+}
+
+// indexDoneMsg reports the end of a background index refresh.
+type indexDoneMsg struct{ err error }
+
+// refreshIndexCmd returns the command that refreshes the index of root in the
+// background (see refreshIndex); the TUI keeps working, only slower, until
+// the result is published.
+func refreshIndexCmd(root string) tea.Cmd {
+	return func() tea.Msg {
+		_, err := refreshIndex(root, false)
+		return indexDoneMsg{err: err}
+	}
+}
+
+// loadIndex reads the saved index of root. An index of another version or
+// notes dir is treated as missing.
+func loadIndex(root string) (*noteIndex, error) {
+	p, err := indexPath(root)
+	if err != nil {
+		return nil, err
+	}
+	// #nosec G304 -- p is derived from the user cache dir, not from note content.
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+	ix := &noteIndex{}
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(ix); err != nil {
+		return nil, fmt.Errorf("corrupt index %s: %w", p, err)
+	}
+	if ix.Version != indexVersion || ix.Root != root {
+		return nil, errors.New("stale index")
+	}
+	ix.byPath = make(map[string]int32, len(ix.Docs))
+	for id, d := range ix.Docs {
+		if d.Path != "" {
+			ix.byPath[d.Path] = int32(id)
+		}
+	}
+	return ix, nil
+}
+
+// save writes the index atomically with 0600 permissions.
+func (ix *noteIndex) save() error {
+	p, err := indexPath(ix.Root)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o700); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(ix); err != nil {
+		return err
+	}
+	return writeFileAtomic(p, buf.Bytes(), 0o600)
+}
+
+// updateIndex returns the index of root derived from old (nil: start empty).
+// old itself is returned when nothing changed; otherwise the result shares
+// the unchanged postings with old without modifying it.
+func updateIndex(old *noteIndex, root string) (*noteIndex, indexStats, error) {
+	var stats indexStats
+	if old == nil {
+		old = &noteIndex{Version: indexVersion, Root: root, Postings: map[string][]int32{}, byPath: map[string]int32{}}
+	}
+
+	type change struct {
+		path string
+		info fs.FileInfo
+	}
+	var changed []change
+	seen := make(map[string]bool, len(old.byPath))
+	err := walkNoteFiles(root, func(p string, info fs.FileInfo) {
+		seen[p] = true
+		stats.Notes++
+		if id, ok := old.byPath[p]; ok && old.Docs[id].fresh(info) {
+			return
+		}
+		changed = append(changed, change{p, info})
+	})
+	if err != nil {
+		return nil, stats, err
+	}
+	var removed []int32
+	for p, id := range old.byPath {
+		if !seen[p] {
+			removed = append(removed, id)
+		}
+	}
+	if len(changed) == 0 && len(removed) == 0 && old.Docs != nil {
+		return old, stats, nil
+	}
+
+	ix := &noteIndex{
+		Version:  indexVersion,
+		Root:     root,
+		Docs:     append([]indexDoc(nil), old.Docs...),
+		Postings: make(map[string][]int32, len(old.Postings)),
+		byPath:   make(map[string]int32, len(old.byPath)),
+	}
+	for w, ids := range old.Postings {
+		ix.Postings[w] = ids[:len(ids):len(ids)] // appends must not write into old's arrays
+	}
+	for p, id := range old.byPath {
+		ix.byPath[p] = id
+	}
+	drop := func(id int32) {
+		delete(ix.byPath, ix.Docs[id].Path)
+		ix.Docs[id] = indexDoc{} // its postings are skipped from now on
+	}
+	for _, id := range removed {
+		drop(id)
+	}
+	stats.Removed = len(removed)
+	for _, c := range changed {
+		if id, ok := ix.byPath[c.path]; ok {
+			drop(id)
+		}
+		sc := scanNote(c.path, nil, true)
+		id := int32(len(ix.Docs))
+		ix.Docs = append(ix.Docs, indexDoc{Path: c.path, ModTime: c.info.ModTime().UnixNano(), Size: c.info.Size(), Title: sc.Title})
+		ix.byPath[c.path] = id
+		for w := range noteWords(sc.Lines) {
+			ix.Postings[w] = append(ix.Postings[w], id)
+		}
+		stats.Updated++
+	}
+	if len(ix.Docs) > 2*len(ix.byPath)+64 {
+		ix.compact()
+	}
+	return ix, stats, nil
+}
+
+// compact renumbers the live notes so the slots and postings of removed
+// notes are reclaimed. Only called on an index that is not yet published.
+func (ix *noteIndex) compact() {
+	renum := make([]int32, len(ix.Docs))
+	docs := make([]indexDoc, 0, len(ix.byPath))
+	for id, d := range ix.Docs {
+		renum[id] = -1
+		if d.Path != "" {
+			renum[id] = int32(len(docs))
+			ix.byPath[d.Path] = renum[id]
+			docs = append(docs, d)
+		}
+	}
+	for w, ids := range ix.Postings {
+		var live []int32
+		for _, id := range ids {
+			if renum[id] >= 0 {
+				live = append(live, renum[id])
+			}
+		}
+		if len(live) == 0 {
+			delete(ix.Postings, w)
+			continue
+		}
+		ix.Postings[w] = live
+	}
+	ix.Docs = docs
+}
+
+// fresh reports whether the entry still describes the file with info.
+func (d indexDoc) fresh(info fs.FileInfo) bool {
+	return d.Path != "" && d.ModTime == info.ModTime().UnixNano() && d.Size == info.Size()
+}
+
+// lookup returns the ID of the note at p when its entry is up to date.
+// Symlinks are never indexed (see walkNoteFiles).
+func (ix *noteIndex) lookup(p string, info fs.FileInfo) (int32, bool) {
+	if ix == nil || info.Mode()&fs.ModeSymlink != 0 {
+		return 0, false
+	}
+	id, ok := ix.byPath[p]
+	if !ok || !ix.Docs[id].fresh(info) {
+		return 0, false
+	}
+	return id, true
+}
+
+// walkNoteFiles calls fn for every note file below root that the tree would
+// list (same filtering as readDirNodes). Symlinked notes are left out: editing
+// the target does not change the link's mtime or size, so their entries could
+// not be kept fresh (readDirNodes always reads them).
+func walkNoteFiles(root string, fn func(p string, info fs.FileInfo)) error {
+	tmplDir, _ := templatesDir()
+	return filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if d != nil && d.IsDir() && p != root {
+				return fs.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() {
+			if p != root && (hiddenDir(d.Name(), p, tmplDir) || !isListableDir(p)) {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.Type()&fs.ModeSymlink != 0 || !allowedExts[strings.ToLower(filepath.Ext(p))] || !isReadableFile(p) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil // vanished or unreadable: skip like readDirNodes
+		}
+		fn(p, info)
+		return nil
+	})
+}
+
+// isWordRune reports whether r is part of an indexed word.
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// noteWords returns the distinct lowercased words of lines.
+func noteWords(lines []string) map[string]bool {
+	words := map[string]bool{}
+	for _, l := range lines {
+		for _, w := range strings.FieldsFunc(strings.ToLower(l), func(r rune) bool { return !isWordRune(r) }) {
+			words[w] = true
+		}
+	}
+	return words
+}
+
+// candidates returns, for every note ID, whether the note may satisfy q; nil
+// means the index cannot rule out any note. Only plain content terms are
+// looked up in the postings: every word-run of such a term must occur inside
+// some word of the note, so notes lacking one cannot match. Title, path and
+// extension terms are checked exactly. A candidate still has to be confirmed
+// by scanning the note.
+func (ix *noteIndex) candidates(q *query) []bool {
+	if q.expr == nil {
+		return nil
+	}
+	return ix.eval(q.expr)
+}
+
+// eval computes the candidate set of e (see candidates).
+func (ix *noteIndex) eval(e *qexpr) []bool {
+	switch e.op {
+	case opAnd:
+		var out []bool
+		for _, k := range e.kids {
+			out = intersect(out, ix.eval(k))
+		}
+		return out
+	case opOr:
+		out := make([]bool, len(ix.Docs))
+		for _, k := range e.kids {
+			set := ix.eval(k)
+			if set == nil {
+				return nil
+			}
+			for id, ok := range set {
+				out[id] = out[id] || ok
+			}
+		}
+		return out
+	case opNot:
+		return nil // a superset cannot be complemented
+	}
+
+	t := e.term
+	if t.field != fieldContent {
+		out := make([]bool, len(ix.Docs))
+		for id, d := range ix.Docs {
+			if d.Path == "" {
+				continue
+			}
+			f := &noteFacts{path: filepath.ToSlash(d.Path), title: d.Title}
+			if rel, err := filepath.Rel(ix.Root, d.Path); err == nil {
+				f.path = filepath.ToSlash(rel)
+			}
+			if f.title == "" {
+				f.title = filepath.Base(d.Path)
+			}
+			out[id] = e.eval(f)
+		}
+		return out
+	}
+	if t.regex {
+		return nil
+	}
+	var out []bool
+	// Indexed words are lowercased, so case-sensitive and whole-word terms
+	// still narrow the notes down; scanNote checks them exactly.
+	for _, frag := range strings.FieldsFunc(strings.ToLower(t.text), func(r rune) bool { return !isWordRune(r) }) {
+		set := make([]bool, len(ix.Docs))
+		for w, ids := range ix.Postings {
+			if strings.Contains(w, frag) {
+				for _, id := range ids {
+					set[id] = true
+				}
+			}
+		}
+		out = intersect(out, set)
+	}
+	return out
+}
+
+// intersect returns the notes in both candidate sets (nil is "all notes").
+// a is modified in place when it is not nil.
+func intersect(a, b []bool) []bool {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	for id := range a {
+		a[id] = a[id] && b[id]
+	}
+	return a
+}
+
+// cmdIndex implements `nnav index [--rebuild]`: update the search index
+// (or build it from scratch) and report what changed.
+func cmdIndex(args []string) error {
+	fs := flag.NewFlagSet("index", flag.ContinueOnError)
+	rebuild := fs.Bool("rebuild", false, "discard the saved index and re-read every note")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: nnav index [--rebuild]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return errors.New("unexpected arguments")
+	}
+	root, err := notesRoot()
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	stats, err := refreshIndex(root, *rebuild)
+	if err != nil {
+		return err
+	}
+	p, _ := indexPath(root)
+	fmt.Printf("indexed %s (%d updated, %d removed) in %s\n", countNotes(stats.Notes), stats.Updated, stats.Removed, time.Since(start).Round(time.Millisecond))
+	fmt.Println(p)
+	return nil
+}
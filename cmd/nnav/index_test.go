@@ -0,0 +1,214 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testIndex builds an index of root from scratch.
+func testIndex(t *testing.T, root string) *noteIndex {
+	t.Helper()
+	ix, _, err := updateIndex(nil, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ix
+}
+
+func TestIndexCandidatesSuperset(t *testing.T) {
+	root := testNotes(t, map[string]string{
+		"k8s.md":         "# Kubernetes\nDeploying with Helm charts.\n",
+		"ops/tf.md":      "# Terraform\nstate_file locking and modules\n",
+		"ops/ansible.md": "Ansible playbooks, no helm here.\n",
+		"café.md":        "Café notes about Go.\n",
+		"draft.txt":      "a draft of the release notes\n",
+	})
+	ix := testIndex(t, root)
+
+	queries := []string{
+		"helm", "HELM", "elm", "helm charts", `"helm charts"`, "helm -ansible",
+		"terraform OR ansible", "NOT helm", "state_file", "file", "café",
+		"title:kubernetes", "path:ops", "ext:txt", "notes (go OR draft)",
+		"nothing-like-this",
+	}
+	for _, text := range queries {
+		for _, opts := range []searchOpts{{}, {word: true}, {caseSensitive: true}} {
+			q, err := compileQuery(text, opts)
+			if err != nil {
+				t.Fatalf("compileQuery(%q): %v", text, err)
+			}
+			cand := ix.candidates(q)
+			for id, d := range ix.Docs {
+				if cand == nil || cand[id] {
+					continue
+				}
+				if scanNote(d.Path, q, false).Match {
+					t.Errorf("%q %+v: %s matches but is not a candidate", text, opts, d.Path)
+				}
+			}
+		}
+	}
+}
+
+func TestIndexCandidatesNarrow(t *testing.T) {
+	root := testNotes(t, map[string]string{
+		"a.md": "helm charts\n",
+		"b.md": "terraform state\n",
+	})
+	ix := testIndex(t, root)
+	tests := []struct {
+		query string
+		want  []string // nil: every note is a candidate
+	}{
+		{"helm", []string{"a.md"}},
+		{"elm", []string{"a.md"}},
+		{"helm OR state", []string{"a.md", "b.md"}},
+		{"helm terraform", []string{}},
+		{"NOT helm", nil},
+		{"path:b", []string{"b.md"}},
+	}
+	for _, tt := range tests {
+		q, err := compileQuery(tt.query, searchOpts{})
+		if err != nil {
+			t.Fatalf("compileQuery(%q): %v", tt.query, err)
+		}
+		cand := ix.candidates(q)
+		if tt.want == nil {
+			if cand != nil {
+				t.Errorf("candidates(%q) = %v, want all notes", tt.query, cand)
+			}
+			continue
+		}
+		var got []string
+		for id, ok := range cand {
+			if ok {
+				got = append(got, filepath.Base(ix.Docs[id].Path))
+			}
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("candidates(%q) = %v, want %v", tt.query, got, tt.want)
+			continue
+		}
+		for _, name := range tt.want {
+			if id, ok := ix.byPath[filepath.Join(root, name)]; !ok || !cand[id] {
+				t.Errorf("candidates(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestUpdateIndexIncremental(t *testing.T) {
+	root := testNotes(t, map[string]string{
+		"keep.md":   "unchanged words\n",
+		"edit.md":   "old content\n",
+		"remove.md": "doomed words\n",
+	})
+	old := testIndex(t, root)
+
+	same, stats, err := updateIndex(old, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if same != old || stats.Updated != 0 || stats.Removed != 0 || stats.Notes != 3 {
+		t.Fatalf("unchanged notes: got a new index or stats %+v", stats)
+	}
+
+	edit := filepath.Join(root, "edit.md")
+	if err := os.WriteFile(edit, []byte("brand new content here\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(root, "remove.md")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "add.md"), []byte("fresh words\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	ix, stats, err := updateIndex(old, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Notes != 3 || stats.Updated != 2 || stats.Removed != 1 {
+		t.Errorf("stats = %+v, want 3 notes, 2 updated, 1 removed", stats)
+	}
+	if len(ix.byPath) != 3 {
+		t.Errorf("index has %d notes, want 3", len(ix.byPath))
+	}
+	if _, ok := ix.byPath[filepath.Join(root, "remove.md")]; ok {
+		t.Error("removed note is still indexed")
+	}
+	if id, ok := ix.byPath[filepath.Join(root, "keep.md")]; !ok || id != old.byPath[filepath.Join(root, "keep.md")] {
+		t.Error("unchanged note was renumbered")
+	}
+
+	has := func(ix *noteIndex, word, name string) bool {
+		id, ok := ix.byPath[filepath.Join(root, name)]
+		if !ok {
+			return false
+		}
+		for _, w := range ix.Postings[word] {
+			if w == id {
+				return true
+			}
+		}
+		return false
+	}
+	if !has(ix, "brand", "edit.md") || !has(ix, "fresh", "add.md") {
+		t.Error("new words are not indexed")
+	}
+	if has(ix, "old", "edit.md") {
+		t.Error("edited note still listed under a word it lost")
+	}
+	// The published index must not be changed by an update.
+	if !has(old, "old", "edit.md") || !has(old, "doomed", "remove.md") || len(old.Docs) != 3 {
+		t.Error("updateIndex modified the old index")
+	}
+}
+
+func TestIndexCompact(t *testing.T) {
+	root := testNotes(t, map[string]string{
+		"a.md": "alpha shared\n",
+		"b.md": "bravo shared\n",
+		"c.md": "charlie shared\n",
+	})
+	ix := testIndex(t, root)
+	if err := os.Remove(filepath.Join(root, "a.md")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "c.md"), []byte("charlie delta\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	ix, _, err := updateIndex(ix, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ix.Docs) == len(ix.byPath) {
+		t.Fatal("expected dead slots before compacting")
+	}
+
+	ix.compact()
+	if len(ix.Docs) != 2 || len(ix.byPath) != 2 {
+		t.Fatalf("compacted index has %d docs, %d paths; want 2", len(ix.Docs), len(ix.byPath))
+	}
+	for p, id := range ix.byPath {
+		if ix.Docs[id].Path != p {
+			t.Errorf("byPath[%s] = %d, which is %s", p, id, ix.Docs[id].Path)
+		}
+	}
+	for w, ids := range ix.Postings {
+		for _, id := range ids {
+			if int(id) >= len(ix.Docs) || ix.Docs[id].Path == "" {
+				t.Errorf("posting %q refers to dead note %d", w, id)
+			}
+		}
+	}
+	if _, ok := ix.Postings["alpha"]; ok {
+		t.Error("words of removed notes are kept")
+	}
+	for word, want := range map[string]int{"shared": 1, "bravo": 1, "charlie": 1, "delta": 1} {
+		if got := len(ix.Postings[word]); got != want {
+			t.Errorf("%q is listed for %d notes, want %d", word, got, want)
+		}
+	}
+}
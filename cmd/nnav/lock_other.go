@@ -0,0 +1,9 @@
+//go:build !unix
+
+package main
+
+// lockNote is a no-op where flock is not available: concurrent captures to
+// the same note are not serialized there.
+func lockNote(p string) (func(), error) {
+	return func() {}, nil
+}
@@ -1,17 +1,63 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 )
 
 func main() {
-	var searchTerm string
-	// Optional search term filters the tree to notes containing the keyword.
+	// Subcommands (e.g. `nnav today`) run without the TUI. Their names take
+	// precedence over search terms; `nnav -- today` searches for "today".
 	if len(os.Args) > 1 {
-		searchTerm = os.Args[1]
+		if run, ok := subcommands[os.Args[1]]; ok {
+			if err := run(os.Args[2:]); err != nil {
+				fmt.Fprintln(os.Stderr, "nnav:", err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
+	// Optional search query filters the tree to matching notes (see query.go;
+	// terms are patterns with --regex; smart-case unless --case-sensitive),
+	// optionally limited by date and size.
+	fs := flag.NewFlagSet("nnav", flag.ExitOnError)
+	regex := fs.Bool("regex", false, "treat the search terms as regular expressions")
+	caseSensitive := fs.Bool("case-sensitive", false, "match case in every term (default: smart-case)")
+	word := fs.Bool("word", false, "only match whole words")
+	in := fs.String("in", "content", "what unqualified terms match: name, title or content")
+	since := fs.String("since", "", "only notes modified since an age (7d, 2w, 6m, 1y) or date (2026-01-01)")
+	before := fs.String("before", "", "only notes last modified before an age or date")
+	larger := fs.String("larger-than", "", "only notes larger than a size (e.g. 100k, 2m)")
+	smaller := fs.String("smaller-than", "", "only notes smaller than a size")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: nnav [--regex] [--case-sensitive] [--word] [--in name|title|content] [--since <when>] [--before <when>] [--larger-than <size>] [--smaller-than <size>] [query]")
+		fmt.Fprintln(fs.Output(), "       nnav "+strings.Join(subcommandNames(), "|")+" [args]")
+		fmt.Fprintln(fs.Output(), "A first argument naming a subcommand runs it; use `nnav -- <word>` to search for that word instead.")
+		fmt.Fprintln(fs.Output(), "Query words starting with - that are not flags (e.g. -draft) exclude notes; `--` also ends the flags.")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(queryArgs(fs, os.Args[1:]))
+	filter, err := newNoteFilter(*since, *before, *larger, *smaller, time.Now())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "nnav:", err)
+		os.Exit(1)
+	}
+	scope, err := parseSearchScope(*in)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "nnav:", err)
+		os.Exit(1)
+	}
+	opts := searchOpts{regex: *regex, caseSensitive: *caseSensitive, word: *word, scope: scope, filter: filter}
+	q, err := compileQuery(strings.Join(fs.Args(), " "), opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "nnav:", err)
+		os.Exit(1)
 	}
 
 	// Determine the root directory where notes are stored.
@@ -22,9 +68,14 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Use the saved full-text index right away so the tree and searches can
+	// skip reading unchanged notes; the TUI brings it up to date in the
+	// background. nnav still works without it, only slower.
+	publishSavedIndex(rootPath)
+
 	// Build an in-memory tree representation of the notes directory.
 	// This structure drives the TUI navigation model.
-	root, err := buildTree(rootPath, searchTerm)
+	root, err := buildTree(rootPath, q)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, "nnav:", err)
 		os.Exit(1)
@@ -33,7 +84,7 @@ func main() {
 	// Initialize the Bubble Tea program with the model created from the notes tree.
 	// tea.WithAltScreen() ensures the TUI runs in a fullscreen alternate buffer
 	// (so it doesn't clutter the user's normal terminal scrollback).
-	p := tea.NewProgram(newModel(root, searchTerm), tea.WithAltScreen())
+	p := tea.NewProgram(newModel(root, q, opts), tea.WithAltScreen())
 
 	// Start the program’s event loop.
 	// If the loop exits with an error, report it to stderr and terminate.
@@ -43,3 +94,27 @@ func main() {
 	}
 }
 
+// queryArgs inserts "--" before the first argument that looks like a flag but
+// names none of fs, so queries such as `nnav -draft` or `nnav --since 1w
+// -wip` are searches rather than flag errors. Values of non-boolean flags
+// given as a separate argument are skipped.
+func queryArgs(fs *flag.FlagSet, args []string) []string {
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		if a == "--" || len(a) < 2 || a[0] != '-' {
+			return args
+		}
+		name, _, hasValue := strings.Cut(strings.TrimLeft(a, "-"), "=")
+		if name == "h" || name == "help" {
+			continue
+		}
+		f := fs.Lookup(name)
+		if f == nil {
+			return append(append(args[:i:i], "--"), args[i:]...)
+		}
+		if b, ok := f.Value.(interface{ IsBoolFlag() bool }); !hasValue && !(ok && b.IsBoolFlag()) {
+			i++ // the flag's value
+		}
+	}
+	return args
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// titleStem returns the file stem a note titled title should have: the slug
+// of the title, behind the note's Zettelkasten ID when it has one. Returns ""
+// when the title has nothing to slugify.
+func titleStem(p, title string) string {
+	slug := slugify(title)
+	if slug == "" {
+		return ""
+	}
+	if id, _ := splitNoteID(strings.TrimSuffix(filepath.Base(p), filepath.Ext(p))); id != "" {
+		return id + "-" + slug
+	}
+	return slug
+}
+
+// stemMatches reports whether stem already follows want. A "-2", "-3", …
+// suffix only counts when wantTaken reports that the plain name is in use,
+// the one case in which uniqueName would have added it.
+func stemMatches(stem, want string, wantTaken func() bool) bool {
+	if stem == want {
+		return true
+	}
+	n, ok := strings.CutPrefix(stem, want+"-")
+	if !ok {
+		return false
+	}
+	k, err := strconv.Atoi(n)
+	return err == nil && k >= 2 && strconv.Itoa(k) == n && wantTaken()
+}
+
+// planNormalize compares the file name of every note below dir with the slug
+// of its first heading (as found by scanTitle) and plans a rename for each
+// mismatch. Names that are taken, on disk or earlier in the plan, get a
+// numeric suffix. Notes without a heading are counted in skipped.
+func planNormalize(dir string) (items []renameItem, skipped int, err error) {
+	taken := map[string]map[string]bool{}
+	err = walkNotes(dir, nil, func(n *Node) error {
+		want := titleStem(n.Path, strings.TrimSpace(n.Title))
+		if want == "" {
+			skipped++
+			return nil
+		}
+		ext := filepath.Ext(n.Name)
+		parent := filepath.Dir(n.Path)
+		if taken[parent] == nil {
+			taken[parent] = map[string]bool{}
+		}
+		safeParent, ok := safePathWithinNotes(parent)
+		if !ok {
+			return fmt.Errorf("outside notes dir: %s", parent)
+		}
+		wantTaken := func() bool {
+			if taken[parent][want+ext] {
+				return true
+			}
+			_, err := os.Lstat(filepath.Join(safeParent, want+ext))
+			return err == nil
+		}
+		if stemMatches(strings.TrimSuffix(n.Name, ext), want, wantTaken) {
+			return nil
+		}
+		name := uniqueName(safeParent, want, ext, taken[parent])
+		it := renameItem{From: n.Path, To: filepath.Join(parent, name)}
+		if name != want+ext {
+			it.Note = want + ext + " is taken"
+		}
+		items = append(items, it)
+		return nil
+	})
+	return items, skipped, err
+}
+
+// cmdNormalize implements `nnav normalize [--dir <dir>] [--dry-run] [--yes]`:
+// report notes whose file name does not match the slug of their title and
+// offer to rename them. The renames are logged as one undoable change.
+func cmdNormalize(args []string) error {
+	fs := flag.NewFlagSet("normalize", flag.ContinueOnError)
+	dir := fs.String("dir", "", "only check notes below this directory, relative to the notes dir")
+	dryRun := fs.Bool("dry-run", false, "only report mismatches")
+	yes := fs.Bool("yes", false, "rename without asking for confirmation")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: nnav normalize [--dir <dir>] [--dry-run] [--yes]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return errors.New("unexpected arguments")
+	}
+	root, err := notesRoot()
+	if err != nil {
+		return err
+	}
+	from, err := notesSubdir(*dir)
+	if err != nil {
+		return err
+	}
+
+	items, skipped, err := planNormalize(from)
+	if err != nil {
+		return err
+	}
+	if skipped > 0 {
+		fmt.Printf("skipped %s without a heading\n", countNotes(skipped))
+	}
+	if len(items) == 0 {
+		fmt.Println("all file names match their titles")
+		return nil
+	}
+	rel := func(p string) string {
+		r, _ := filepath.Rel(root, p)
+		return r
+	}
+	fmt.Printf("%s with a file name that does not match the title:\n", countNotes(len(items)))
+	for _, line := range renamePreview(items, rel) {
+		fmt.Println("  " + line)
+	}
+	if *dryRun {
+		return nil
+	}
+	if !*yes && !askYes("rename "+countNotes(len(items))+"?") {
+		return errors.New("cancelled")
+	}
+	ops, err := applyRename(items)
+	if len(ops) > 0 {
+		if rerr := recordChange("normalize "+countNotes(len(ops)), ops...); rerr != nil {
+			fmt.Fprintln(os.Stderr, "nnav: undo log:", rerr)
+		}
+		fmt.Printf("renamed %s\n", countNotes(len(ops)))
+	}
+	return err
+}
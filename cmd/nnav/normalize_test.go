@@ -0,0 +1,51 @@
+package main
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestPlanNormalize(t *testing.T) {
+	root := testNotes(t, map[string]string{
+		"meeting-2024.md":  "# Meeting\n",
+		"plan-3.md":        "# Plan\n",
+		"notes.md":         "# Notes\n",
+		"notes-2.md":       "# Notes\n",
+		"idea-2.md":        "# Idea\n",
+		"todo-02.md":       "# Todo\n",
+		"todo.md":          "# Something else\n",
+		"ok.md":            "# OK\n",
+		"no-heading.md":    "just text\n",
+		"a/report.md":      "# Report\n",
+		"a/old-report.md":  "# Report\n",
+		"a/report-copy.md": "# Report\n",
+	})
+	items, skipped, err := planNormalize(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, it := range items {
+		from, _ := filepath.Rel(root, it.From)
+		to, _ := filepath.Rel(root, it.To)
+		got = append(got, filepath.ToSlash(from)+" → "+filepath.ToSlash(to))
+	}
+	sort.Strings(got)
+	want := []string{
+		"a/old-report.md → a/report-2.md",
+		"a/report-copy.md → a/report-3.md",
+		"idea-2.md → idea.md", // idea.md is free, so -2 is not a uniqueness suffix
+		"meeting-2024.md → meeting.md",
+		"plan-3.md → plan.md",
+		"todo-02.md → todo-2.md",
+		"todo.md → something-else.md",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("plan:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if skipped != 1 {
+		t.Errorf("skipped = %d, want 1", skipped)
+	}
+}
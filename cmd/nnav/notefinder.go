@@ -0,0 +1,85 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// noteFinderHelp is the footer shown while the note finder is open.
+const noteFinderHelp = "type to filter • ↑/↓ move • <enter> open • <tab> reveal in tree • <esc> cancel"
+
+// notesListedMsg delivers every note of the tree, collected in the
+// background for the note finder.
+type notesListedMsg struct {
+	notes []*Node
+	err   error
+}
+
+// openNoteFinder opens the ctrl+p finder over all notes, including those in
+// directories that were never expanded. Notes are matched on their title and
+// path relative to the notes root; the list is filled in asynchronously.
+func (m *model) openNoteFinder() tea.Cmd {
+	m.openFinder("find note", nil, nil)
+	f := m.finder
+	f.loading = true
+	m.status = noteFinderHelp
+	root := m.root.Path
+	return func() tea.Msg {
+		var notes []*Node
+		err := walkNotes(root, nil, func(n *Node) error {
+			notes = append(notes, n)
+			return nil
+		})
+		return notesListedMsg{notes: notes, err: err}
+	}
+}
+
+// fillNoteFinder installs the collected notes into the open note finder.
+func (m *model) fillNoteFinder(msg notesListedMsg) {
+	f := m.finder
+	if f == nil || !f.loading {
+		return // finder closed in the meantime
+	}
+	if msg.err != nil {
+		m.finder = nil
+		m.status = "find failed: " + msg.err.Error()
+		return
+	}
+	items := make([]string, len(msg.notes))
+	for i, n := range msg.notes {
+		rel, err := filepath.Rel(m.root.Path, n.Path)
+		if err != nil {
+			rel = n.Path
+		}
+		items[i] = rel
+		if t := strings.TrimSpace(n.Title); t != "" {
+			items[i] = t + "  " + rel
+		}
+	}
+	notes := msg.notes
+	f.onPick = func(m *model, i int) tea.Cmd {
+		return m.openInEditor(notes[i].Path)
+	}
+	f.onAlt = func(m *model, i int) tea.Cmd {
+		m.revealAnywhere(notes[i].Path)
+		return nil
+	}
+	f.setItems(items)
+}
+
+// revealAnywhere moves the cursor to p, dropping an active search filter
+// first when p is not part of the filtered tree.
+func (m *model) revealAnywhere(p string) {
+	if m.reveal(p) {
+		return
+	}
+	if m.query != nil {
+		m.clearSearch()
+		if m.reveal(p) {
+			return
+		}
+	}
+	m.status = "not in tree: " + m.displayPath(p)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// pager is a scrollable read-only text view, used to preview changes before
+// they are written. When onConfirm is set, "y" applies the previewed change;
+// keys holds extra actions (e.g. applying a variant of the change) that close
+// the pager like "y" does.
+type pager struct {
+	title     string
+	lines     []string
+	scroll    int
+	onConfirm func(m *model) tea.Cmd
+	keys      map[string]func(m *model) tea.Cmd
+}
+
+// showPager opens a pager; onConfirm may be nil for a plain viewer.
+func (m *model) showPager(title string, lines []string, onConfirm func(m *model) tea.Cmd) {
+	m.pager = &pager{title: title, lines: lines, onConfirm: onConfirm}
+	m.status = "↑/↓ scroll • <esc> close"
+	if onConfirm != nil {
+		m.status = "↑/↓ scroll • <y> apply • <esc> cancel"
+	}
+}
+
+// updatePager handles keys while the pager is open.
+func (m model) updatePager(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	p := m.pager
+	page := max(1, m.height-4)
+	if fn, ok := p.keys[msg.String()]; ok {
+		m.pager = nil
+		m.status = helpText
+		return m, fn(&m)
+	}
+	switch msg.String() {
+	case "esc", "q", "n", "ctrl+c":
+		m.pager = nil
+		m.status = helpText
+		if p.onConfirm != nil {
+			m.status = "cancelled"
+		}
+
+	case "y", "enter":
+		if p.onConfirm == nil {
+			if msg.String() == "enter" {
+				m.pager = nil
+				m.status = helpText
+			}
+			break
+		}
+		m.pager = nil
+		m.status = helpText
+		return m, p.onConfirm(&m)
+
+	case "down", "j":
+		p.scroll++
+	case "up", "k":
+		p.scroll--
+	case "pgdown", " ":
+		p.scroll += page
+	case "pgup":
+		p.scroll -= page
+	}
+	p.scroll = max(0, min(p.scroll, len(p.lines)-page))
+	return m, nil
+}
+
+// view renders the pager using the shared frame layout (no cursor row).
+func (p *pager) view(m model) string {
+	return m.frame(p.title, len(p.lines), func(i int) string {
+		return p.lines[i]
+	}, -1, p.scroll)
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// periodKind describes one family of periodic notes (daily, weekly, monthly).
+//   - name: config prefix and label ("daily" → daily_path, daily_template).
+//   - pattern: default path pattern relative to the notes root.
+//   - start: normalizes a time to the first day of its period.
+//   - step: moves a period start n periods forward (negative = backward).
+//   - span: how many periods to look back/forward when jumping between notes.
+type periodKind struct {
+	name    string
+	pattern string
+	start   func(t time.Time) time.Time
+	step    func(t time.Time, n int) time.Time
+	span    int
+}
+
+// periodKinds lists the supported periodic notes in the order they are matched.
+//
+// Patterns use these tokens: {YYYY} year, {MM} month, {DD} day,
+// {GGGG} ISO week-based year and {WW} ISO week number (both for weekly notes).
+var periodKinds = []periodKind{
+	{
+		name:    "daily",
+		pattern: "journal/{YYYY}/{MM}/{YYYY}-{MM}-{DD}.md",
+		start: func(t time.Time) time.Time {
+			return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+		},
+		step: func(t time.Time, n int) time.Time { return t.AddDate(0, 0, n) },
+		span: 3660,
+	},
+	{
+		name:    "weekly",
+		pattern: "journal/{GGGG}/{GGGG}-W{WW}.md",
+		start: func(t time.Time) time.Time {
+			d := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+			return d.AddDate(0, 0, -((int(d.Weekday()) + 6) % 7)) // back to Monday
+		},
+		step: func(t time.Time, n int) time.Time { return t.AddDate(0, 0, 7*n) },
+		span: 520,
+	},
+	{
+		name:    "monthly",
+		pattern: "journal/{YYYY}/{YYYY}-{MM}.md",
+		start: func(t time.Time) time.Time {
+			return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+		},
+		step: func(t time.Time, n int) time.Time { return t.AddDate(0, n, 0) },
+		span: 120,
+	},
+}
+
+// periodTokenRE finds the date tokens in a path pattern.
+var periodTokenRE = regexp.MustCompile(`\{(YYYY|MM|DD|GGGG|WW)\}`)
+
+// configuredKinds returns periodKinds with path patterns overridden by
+// <name>_path entries in ~/.nnav (e.g. daily_path=diary/{YYYY}-{MM}-{DD}.md).
+func configuredKinds() []periodKind {
+	cfg, _ := loadConfig()
+	kinds := make([]periodKind, len(periodKinds))
+	copy(kinds, periodKinds)
+	for i := range kinds {
+		if v := strings.TrimSpace(cfg[kinds[i].name+"_path"]); v != "" {
+			kinds[i].pattern = v
+		}
+	}
+	return kinds
+}
+
+// periodKindNamed returns the configured kind called name ("daily", "weekly", "monthly").
+func periodKindNamed(name string) (periodKind, error) {
+	for _, k := range configuredKinds() {
+		if k.name == name {
+			return k, nil
+		}
+	}
+	return periodKind{}, fmt.Errorf("unknown periodic note: %q", name)
+}
+
+// template returns the configured template name (<name>_template), if any.
+func (k periodKind) template() string {
+	cfg, _ := loadConfig()
+	return strings.TrimSpace(cfg[k.name+"_template"])
+}
+
+// relPath renders the pattern for the period containing t.
+func (k periodKind) relPath(t time.Time) string {
+	year, week := t.ISOWeek()
+	return periodTokenRE.ReplaceAllStringFunc(k.pattern, func(tok string) string {
+		switch tok {
+		case "{YYYY}":
+			return fmt.Sprintf("%04d", t.Year())
+		case "{MM}":
+			return fmt.Sprintf("%02d", int(t.Month()))
+		case "{DD}":
+			return fmt.Sprintf("%02d", t.Day())
+		case "{GGGG}":
+			return fmt.Sprintf("%04d", year)
+		default: // {WW}
+			return fmt.Sprintf("%02d", week)
+		}
+	})
+}
+
+// parse reports whether rel (relative to the notes root) is a note of this
+// kind and, if so, returns the start of its period.
+func (k periodKind) parse(rel string) (time.Time, bool) {
+	pattern := k.pattern
+	var tokens []string
+	var expr strings.Builder
+	expr.WriteString("^")
+	last := 0
+	for _, loc := range periodTokenRE.FindAllStringSubmatchIndex(pattern, -1) {
+		expr.WriteString(regexp.QuoteMeta(filepath.FromSlash(pattern[last:loc[0]])))
+		tok := pattern[loc[2]:loc[3]]
+		expr.WriteString(fmt.Sprintf(`(\d{%d})`, len(tok)))
+		tokens = append(tokens, tok)
+		last = loc[1]
+	}
+	expr.WriteString(regexp.QuoteMeta(filepath.FromSlash(pattern[last:])))
+	expr.WriteString("$")
+	re, err := regexp.Compile(expr.String())
+	if err != nil {
+		return time.Time{}, false
+	}
+	m := re.FindStringSubmatch(rel)
+	if m == nil {
+		return time.Time{}, false
+	}
+
+	vals := map[string]int{"YYYY": 1, "MM": 1, "DD": 1, "WW": 1}
+	seen := map[string]bool{}
+	for i, tok := range tokens {
+		n, _ := strconv.Atoi(m[i+1])
+		if seen[tok] && vals[tok] != n {
+			return time.Time{}, false // repeated token with conflicting values
+		}
+		vals[tok], seen[tok] = n, true
+	}
+	if seen["GGGG"] {
+		t := isoWeekStart(vals["GGGG"], vals["WW"], time.Local)
+		if y, w := t.ISOWeek(); y != vals["GGGG"] || w != vals["WW"] {
+			return time.Time{}, false // no such week, e.g. W00 or W53 of a 52-week year
+		}
+		return t, true
+	}
+	t := time.Date(vals["YYYY"], time.Month(vals["MM"]), vals["DD"], 0, 0, 0, 0, time.Local)
+	if t.Year() != vals["YYYY"] || int(t.Month()) != vals["MM"] || t.Day() != vals["DD"] {
+		return time.Time{}, false // no such day, e.g. 2026-02-30
+	}
+	return k.start(t), true
+}
+
+// isoWeekStart returns the Monday of ISO week w in ISO year y.
+// January 4th always falls in week 1, which anchors the calculation.
+func isoWeekStart(y, w int, loc *time.Location) time.Time {
+	jan4 := time.Date(y, time.January, 4, 0, 0, 0, 0, loc)
+	week1 := jan4.AddDate(0, 0, -((int(jan4.Weekday()) + 6) % 7))
+	return week1.AddDate(0, 0, 7*(w-1))
+}
+
+// periodicNote resolves the note of kind k for the period containing t.
+// Returns the logical path and the sanitized path validated by safeJoinWithin.
+func periodicNote(k periodKind, t time.Time) (string, string, error) {
+	root, err := notesRoot()
+	if err != nil {
+		return "", "", err
+	}
+	rel, err := k.checkedRelPath(t)
+	if err != nil {
+		return "", "", err
+	}
+	safe, err := safeJoinWithin(root, rel)
+	if err != nil {
+		return "", "", err
+	}
+	return filepath.Join(root, rel), safe, nil
+}
+
+// checkedRelPath is relPath for the period containing t, in OS form, with the
+// extension checked against allowedExts.
+func (k periodKind) checkedRelPath(t time.Time) (string, error) {
+	rel := filepath.FromSlash(k.relPath(k.start(t)))
+	if !allowedExts[strings.ToLower(filepath.Ext(rel))] {
+		return "", fmt.Errorf("%s_path must end in .md or .txt", k.name)
+	}
+	return rel, nil
+}
+
+// ensurePeriodic returns the note of kind k for the period containing t,
+// creating it (and its parent directories, 0700) from the kind's template when
+// it does not exist yet. created reports whether a new note was written.
+func ensurePeriodic(k periodKind, t time.Time) (p string, created bool, err error) {
+	p, safe, err := periodicNote(k, t)
+	if err != nil {
+		return "", false, err
+	}
+	if _, err := os.Stat(safe); err == nil {
+		return p, false, nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return "", false, err
+	}
+	if err := os.MkdirAll(filepath.Dir(safe), 0o700); err != nil {
+		return "", false, err
+	}
+	if _, err := createNoteAt(filepath.Dir(p), filepath.Base(p), "", k.template(), k.start(t)); err != nil {
+		return "", false, err
+	}
+	return p, true, nil
+}
+
+// findPeriodic locates the closest existing periodic note before (dir < 0) or
+// after (dir > 0) the note at p. When p is not a periodic note, the search
+// starts from today's daily note. Returns "" when nothing is found in range.
+// Candidates are only stat()ed; opening them still goes through the usual checks.
+func findPeriodic(p string, dir int) (string, error) {
+	root, err := notesRoot()
+	if err != nil {
+		return "", err
+	}
+	kinds := configuredKinds()
+	k, from := kinds[0], kinds[0].start(time.Now())
+	if rel, err := filepath.Rel(root, p); err == nil {
+		for _, kind := range kinds {
+			if t, ok := kind.parse(rel); ok {
+				k, from = kind, t
+				break
+			}
+		}
+	}
+	for i := 1; i <= k.span; i++ {
+		rel, err := k.checkedRelPath(k.step(from, dir*i))
+		if err != nil {
+			return "", err
+		}
+		cand := filepath.Join(root, rel)
+		if info, err := os.Stat(cand); err == nil && !info.IsDir() {
+			return cand, nil
+		}
+	}
+	return "", nil
+}
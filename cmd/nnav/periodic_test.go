@@ -0,0 +1,112 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestIsoWeekStart(t *testing.T) {
+	tests := []struct {
+		year, week int
+		want       string
+	}{
+		{2026, 1, "2025-12-29"},
+		{2026, 42, "2026-10-12"},
+		{2021, 1, "2021-01-04"},
+		{2020, 53, "2020-12-28"},
+		{2015, 53, "2015-12-28"},
+		{2024, 1, "2024-01-01"},
+		{2027, 52, "2027-12-27"},
+	}
+	for _, tt := range tests {
+		got := isoWeekStart(tt.year, tt.week, time.UTC)
+		if s := got.Format("2006-01-02"); s != tt.want {
+			t.Errorf("isoWeekStart(%d, %d) = %s, want %s", tt.year, tt.week, s, tt.want)
+		}
+		if got.Weekday() != time.Monday {
+			t.Errorf("isoWeekStart(%d, %d) is a %s", tt.year, tt.week, got.Weekday())
+		}
+		if y, w := got.ISOWeek(); y != tt.year || w != tt.week {
+			t.Errorf("isoWeekStart(%d, %d) is in week %d of %d", tt.year, tt.week, w, y)
+		}
+	}
+}
+
+func testKind(t *testing.T, name string) periodKind {
+	t.Helper()
+	for _, k := range periodKinds {
+		if k.name == name {
+			return k
+		}
+	}
+	t.Fatalf("no periodic kind %q", name)
+	return periodKind{}
+}
+
+func TestPeriodKindParse(t *testing.T) {
+	day := func(s string) time.Time {
+		d, err := time.ParseInLocation("2006-01-02", s, time.Local)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+	tests := []struct {
+		kind, pattern, rel string
+		want               string // "" when rel is not a note of the kind
+	}{
+		{"daily", "", "journal/2026/10/2026-10-16.md", "2026-10-16"},
+		{"daily", "", "journal/2026/10/2026-10-16.txt", ""},
+		{"daily", "", "journal/2026/10/2026-11-16.md", ""}, // conflicting months
+		{"daily", "", "journal/2026/02/2026-02-30.md", ""},
+		{"daily", "", "journal/2026/10/26-10-16.md", ""},
+		{"daily", "", "notes/2026-10-16.md", ""},
+		{"daily", "diary/{YYYY}{MM}{DD}.md", "diary/20261016.md", "2026-10-16"},
+		{"daily", "diary/{DD}.{MM}.{YYYY}.md", "diary/16.10.2026.md", "2026-10-16"},
+		{"daily", "diary/{DD}.{MM}.{YYYY}.md", "diary/16x10x2026.md", ""}, // dots are literal
+		{"weekly", "", "journal/2026/2026-W42.md", "2026-10-12"},
+		{"weekly", "", "journal/2026/2026-W01.md", "2025-12-29"},
+		{"weekly", "", "journal/2020/2020-W53.md", "2020-12-28"},
+		{"weekly", "", "journal/2026/2026-W53.md", "2026-12-28"},
+		{"weekly", "", "journal/2025/2025-W53.md", ""},
+		{"weekly", "", "journal/2026/2026-W00.md", ""},
+		{"weekly", "", "journal/2026/2027-W01.md", ""},
+		{"monthly", "", "journal/2026/2026-10.md", "2026-10-01"},
+		{"monthly", "", "journal/2026/2026-13.md", ""},
+		{"monthly", "", "journal/2026/2026-10-16.md", ""},
+	}
+	for _, tt := range tests {
+		k := testKind(t, tt.kind)
+		if tt.pattern != "" {
+			k.pattern = tt.pattern
+		}
+		got, ok := k.parse(filepath.FromSlash(tt.rel))
+		if ok != (tt.want != "") {
+			t.Errorf("%s %q: parse(%q) ok = %v, want %v", tt.kind, k.pattern, tt.rel, ok, !ok)
+			continue
+		}
+		if ok && !got.Equal(day(tt.want)) {
+			t.Errorf("%s %q: parse(%q) = %s, want %s", tt.kind, k.pattern, tt.rel, got.Format("2006-01-02"), tt.want)
+		}
+	}
+}
+
+func TestPeriodKindRoundTrip(t *testing.T) {
+	for _, k := range periodKinds {
+		for d := testNow.AddDate(-2, 0, -3); d.Before(testNow.AddDate(1, 0, 0)); d = d.AddDate(0, 0, 5) {
+			rel := filepath.FromSlash(k.relPath(d))
+			got, ok := k.parse(rel)
+			if !ok {
+				t.Errorf("%s: parse(%q) failed", k.name, rel)
+				continue
+			}
+			if want := k.start(d); !got.Equal(want) {
+				t.Errorf("%s: parse(relPath(%s)) = %s, want %s", k.name, d.Format("2006-01-02"), got.Format("2006-01-02"), want.Format("2006-01-02"))
+			}
+			if next := k.step(got, 1); k.relPath(next) == k.relPath(got) || !k.start(next).Equal(next) {
+				t.Errorf("%s: step from %s gives %s", k.name, got.Format("2006-01-02"), next.Format("2006-01-02"))
+			}
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// pickerHelp is the footer shown while the directory picker is open.
+const pickerHelp = "↑/↓ move • → expand • ← collapse • <enter> choose • <esc> cancel"
+
+// dirPicker is a modal tree of directories used to choose a destination.
+// It keeps its own Node tree so expanding or collapsing directories while
+// picking never disturbs the expansion state of the main view; rows are
+// produced by flatten/Visible exactly like the main tree.
+//   - title: heading describing what is being picked for.
+//   - root: directories-only copy of the notes tree (root included as a row).
+//   - onPick: called with the chosen directory path.
+type dirPicker struct {
+	title   string
+	root    *Node
+	visible []Visible
+	cursor  int
+	scroll  int
+	onPick  func(m *model, dir string) tea.Cmd
+}
+
+// openPicker shows the directory picker rooted at the notes directory.
+func (m *model) openPicker(title string, onPick func(m *model, dir string) tea.Cmd) error {
+	root := &Node{Name: m.root.Name, Path: m.root.Path, IsDir: true}
+	if err := expandDirsOnly(root); err != nil {
+		return err
+	}
+	m.picker = &dirPicker{title: title, root: root, onPick: onPick}
+	m.picker.recompute()
+	m.status = pickerHelp
+	return nil
+}
+
+// expandDirsOnly loads n's subdirectories (files are dropped) and expands it.
+func expandDirsOnly(n *Node) error {
+	if err := expandIfNeeded(n, nil); err != nil {
+		return err
+	}
+	dirs := n.Children[:0]
+	for _, c := range n.Children {
+		if c.IsDir {
+			dirs = append(dirs, c)
+		}
+	}
+	n.Children = dirs
+	return nil
+}
+
+// recompute rebuilds the picker rows; the root itself is the first row so the
+// top-level notes directory can be chosen as a destination.
+func (p *dirPicker) recompute() {
+	p.visible = p.visible[:0]
+	flatten(p.root, 0, &p.visible)
+	if p.cursor >= len(p.visible) {
+		p.cursor = len(p.visible) - 1
+	}
+}
+
+// updatePicker handles keys while the picker is open.
+func (m model) updatePicker(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	p := m.picker
+	switch msg.String() {
+	case "esc", "q", "ctrl+c":
+		m.picker = nil
+		m.status = "cancelled"
+
+	case "down", "j":
+		if p.cursor < len(p.visible)-1 {
+			p.cursor++
+		}
+
+	case "up", "k":
+		if p.cursor > 0 {
+			p.cursor--
+		}
+
+	case "right", "l":
+		cur := p.visible[p.cursor].N
+		if !cur.Expanded {
+			if err := expandDirsOnly(cur); err != nil {
+				m.status = "error: " + err.Error()
+			}
+			p.recompute()
+		}
+
+	case "left", "h":
+		cur := p.visible[p.cursor].N
+		if cur.Expanded && cur != p.root {
+			cur.Expanded = false
+			p.recompute()
+		}
+
+	case "enter":
+		dir := p.visible[p.cursor].N.Path
+		m.picker = nil
+		m.status = helpText
+		return m, p.onPick(&m, dir)
+	}
+	p.scroll = scrollWindow(p.cursor, p.scroll, len(p.visible), m.height)
+	return m, nil
+}
+
+// view renders the picker using the shared frame layout.
+func (p *dirPicker) view(m model) string {
+	return m.frame(p.title, len(p.visible), func(i int) string {
+		return renderLine(p.visible[i], false, nil, false)
+	}, p.cursor, p.scroll)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// prompt is a single-line input rendered in place of the footer/status line.
+// While a prompt is open it receives every key press; <enter> submits the
+// value to onSubmit and <esc> cancels without side effects.
+//   - label: text shown before the input (e.g. "new note: ").
+//   - value: the text typed so far.
+//   - confirm: when true the prompt answers on a single key; only "y"/"Y"
+//     submits, anything else cancels. Used for destructive actions.
+//   - onSubmit: applies the answer to the model and may return a command.
+//   - onChange: optional, called after every edit of value (live search).
+//   - onCancel: optional, called when the prompt is cancelled with <esc>.
+//   - keys: optional extra key bindings active while typing (e.g. alt+r).
+//   - hint: optional message shown after the input (e.g. a pattern error).
+type prompt struct {
+	label    string
+	value    string
+	confirm  bool
+	onSubmit func(m *model, value string) tea.Cmd
+	onChange func(m *model, value string) tea.Cmd
+	onCancel func(m *model)
+	keys     map[string]func(m *model) tea.Cmd
+	hint     string
+}
+
+// ask opens a text prompt with an optional pre-filled value.
+func (m *model) ask(label, initial string, onSubmit func(m *model, value string) tea.Cmd) {
+	m.prompt = &prompt{label: label, value: initial, onSubmit: onSubmit}
+}
+
+// confirm opens a yes/no prompt; onYes only runs when the user presses "y".
+func (m *model) confirm(question string, onYes func(m *model) tea.Cmd) {
+	m.prompt = &prompt{
+		label:    question + " (y/N) ",
+		confirm:  true,
+		onSubmit: func(m *model, _ string) tea.Cmd { return onYes(m) },
+	}
+}
+
+// updatePrompt routes a key press to the open prompt.
+// The prompt is closed before onSubmit runs so callbacks may open a follow-up prompt.
+func (m model) updatePrompt(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	p := m.prompt
+
+	if p.confirm {
+		m.prompt = nil
+		if s := msg.String(); s == "y" || s == "Y" {
+			return m, p.onSubmit(&m, "y")
+		}
+		m.status = "cancelled"
+		return m, nil
+	}
+
+	if f, ok := p.keys[msg.String()]; ok {
+		return m, f(&m)
+	}
+
+	before := p.value
+	switch msg.Type {
+	case tea.KeyEsc, tea.KeyCtrlC:
+		m.prompt = nil
+		m.status = "cancelled"
+		if p.onCancel != nil {
+			p.onCancel(&m)
+		}
+	case tea.KeyEnter:
+		m.prompt = nil
+		m.status = helpText
+		return m, p.onSubmit(&m, p.value)
+	case tea.KeyBackspace:
+		if r := []rune(p.value); len(r) > 0 {
+			p.value = string(r[:len(r)-1])
+		}
+	case tea.KeyCtrlU:
+		p.value = ""
+	case tea.KeySpace:
+		p.value += " "
+	case tea.KeyRunes:
+		p.value += string(msg.Runes)
+	}
+	if p.onChange != nil && p.value != before {
+		return m, p.onChange(&m, p.value)
+	}
+	return m, nil
+}
+
+// view renders the prompt with a block cursor after the typed value.
+func (p *prompt) view() string {
+	if p.confirm {
+		return p.label
+	}
+	if p.hint != "" {
+		return p.label + p.value + "█  " + p.hint
+	}
+	return p.label + p.value + "█"
+}
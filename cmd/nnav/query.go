@@ -0,0 +1,671 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// searchOpts are the switches that change how a search text is matched.
+//   - regex: terms are Go regular expressions (--regex, <alt+r>).
+//   - caseSensitive: match case in every term (--case-sensitive, <alt+c>).
+//     Without it, terms are smart-case: a term with an upper-case letter
+//     matches case, an all-lower-case term ignores it.
+//   - word: terms only match whole words (--word, <alt+w>).
+//   - scope: what unqualified terms match (--in, <alt+s>).
+//   - filter: date and size bounds applied to every note (--since, <f>, …).
+type searchOpts struct {
+	regex         bool
+	caseSensitive bool
+	word          bool
+	scope         searchScope
+	filter        noteFilter
+}
+
+// searchScope is what the terms of a search without a field qualifier are
+// matched against; qualified terms (title:x, path:x, ext:x) are not affected.
+type searchScope int
+
+// Search scopes, in the order <alt+s> cycles through them.
+const (
+	scopeContent searchScope = iota // the note's contents (the default)
+	scopeTitle                      // the note's title, as title:x
+	scopeName                       // the path and file name, as path:x
+)
+
+// scopeNames are the values of --in, indexed by searchScope.
+var scopeNames = []string{"content", "title", "name"}
+
+// parseSearchScope parses the value of --in.
+func parseSearchScope(s string) (searchScope, error) {
+	for i, name := range scopeNames {
+		if strings.EqualFold(strings.TrimSpace(s), name) {
+			return searchScope(i), nil
+		}
+	}
+	return scopeContent, fmt.Errorf("invalid scope: %q (use name, title or content)", s)
+}
+
+func (s searchScope) String() string { return scopeNames[s] }
+
+// field returns the term field unqualified terms get in scope s.
+func (s searchScope) field() int {
+	switch s {
+	case scopeTitle:
+		return fieldTitle
+	case scopeName:
+		return fieldPath
+	}
+	return fieldContent
+}
+
+// Query syntax, evaluated once per note:
+//
+//	kubernetes AND (helm OR kustomize) -draft title:runbook path:ops/ ext:md
+//
+//   - words and "quoted phrases" match anywhere in the note's contents;
+//   - terms next to each other must all match (AND is implied), OR binds
+//     looser than AND, and parentheses group;
+//   - NOT x or -x excludes notes matching x;
+//   - title:x matches the note's title (its first heading, else the file
+//     name), path:x the path relative to the notes dir, ext:x the extension.
+//
+// AND, OR and NOT are only operators in upper case. A backslash escapes the
+// next character (kept as-is in regex mode, so \( still works in patterns).
+// Terms ignore case unless they contain an upper-case letter (smart-case),
+// so Go does not match "good" (see searchOpts for the other switches). With
+// --in title or --in name, unqualified terms act as title:x or path:x.
+
+// Term fields (see qterm.field).
+const (
+	fieldContent = iota
+	fieldTitle
+	fieldPath
+	fieldExt
+)
+
+// qualifiers maps the field prefixes of the query syntax to term fields.
+var qualifiers = map[string]int{"title": fieldTitle, "path": fieldPath, "ext": fieldExt}
+
+// qterm is a single search term.
+//   - field: what the term is matched against (fieldContent, fieldTitle, …).
+//   - text: the term as typed, without its qualifier or quotes.
+//   - re: the term as a pattern (quoted unless in regex mode), used for
+//     regex and whole-word matching and for highlighting.
+//   - lower: lowercased text for the plain substring fast path.
+//   - regex/fold/word: regex mode, ignore case, whole words only.
+//   - slot: index into the per-note content hits (content terms only).
+type qterm struct {
+	field int
+	text  string
+	re    *regexp.Regexp
+	lower string
+	regex bool
+	fold  bool
+	word  bool
+	slot  int
+}
+
+// match reports whether s contains the term.
+func (t *qterm) match(s string) bool {
+	switch {
+	case t.word:
+		return len(t.find(s, 1)) > 0
+	case t.regex:
+		return t.re.MatchString(s)
+	case t.fold:
+		return strings.Contains(strings.ToLower(s), t.lower)
+	}
+	return strings.Contains(s, t.text)
+}
+
+// find returns the byte ranges of up to n matches of the term in s (all of
+// them when n < 0). In whole-word mode, matches touching a letter, digit or
+// underscore on either side are dropped.
+func (t *qterm) find(s string, n int) [][]int {
+	if !t.word {
+		return t.re.FindAllStringIndex(s, n)
+	}
+	var out [][]int
+	for _, loc := range t.re.FindAllStringIndex(s, -1) {
+		if n >= 0 && len(out) == n {
+			break
+		}
+		if wordBounded(s, loc[0], loc[1]) {
+			out = append(out, loc)
+		}
+	}
+	return out
+}
+
+// wordBounded reports whether s[from:to] is not part of a longer word: the
+// runes just outside it are not word characters where its own edges are.
+func wordBounded(s string, from, to int) bool {
+	if from == to {
+		return false
+	}
+	first, _ := utf8.DecodeRuneInString(s[from:])
+	last, _ := utf8.DecodeLastRuneInString(s[:to])
+	if before, _ := utf8.DecodeLastRuneInString(s[:from]); from > 0 && isWordChar(first) && isWordChar(before) {
+		return false
+	}
+	if after, _ := utf8.DecodeRuneInString(s[to:]); to < len(s) && isWordChar(last) && isWordChar(after) {
+		return false
+	}
+	return true
+}
+
+// isWordChar reports whether r is part of a word for whole-word matching:
+// a letter, digit or underscore, as in regexp's \w.
+func isWordChar(r rune) bool {
+	return isWordRune(r) || r == '_'
+}
+
+// hasUpper reports whether the term text contains an upper-case letter, which
+// makes it case-sensitive under smart-case. In regex mode escapes such as \W
+// or \p{Greek} are not letters of the pattern and are skipped.
+func hasUpper(text string, regex bool) bool {
+	rs := []rune(text)
+	for i := 0; i < len(rs); i++ {
+		if regex && rs[i] == '\\' && i+1 < len(rs) {
+			i++
+			switch {
+			case (rs[i] != 'p' && rs[i] != 'P') || i+1 == len(rs):
+			case rs[i+1] == '{':
+				for i < len(rs) && rs[i] != '}' {
+					i++
+				}
+			default:
+				i++ // one-letter class such as \pL
+			}
+			continue
+		}
+		if unicode.IsUpper(rs[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// Expression node kinds (see qexpr.op).
+const (
+	opTerm = iota
+	opAnd
+	opOr
+	opNot
+)
+
+// qexpr is a node of a parsed query: a term, or an operator over kids.
+type qexpr struct {
+	op   int
+	term *qterm
+	kids []*qexpr
+}
+
+// noteFacts is what a query is evaluated against for one note.
+//   - path: path relative to the notes dir, with forward slashes.
+//   - title: first heading, or the file name when the note has none.
+//   - hits: for every content term (by slot), whether some line matched.
+type noteFacts struct {
+	path  string
+	title string
+	hits  []bool
+}
+
+// eval reports whether a note with the given facts satisfies the expression.
+func (e *qexpr) eval(f *noteFacts) bool {
+	switch e.op {
+	case opAnd:
+		for _, k := range e.kids {
+			if !k.eval(f) {
+				return false
+			}
+		}
+		return true
+	case opOr:
+		for _, k := range e.kids {
+			if k.eval(f) {
+				return true
+			}
+		}
+		return false
+	case opNot:
+		return !e.kids[0].eval(f)
+	}
+	t := e.term
+	switch t.field {
+	case fieldTitle:
+		return t.match(f.title)
+	case fieldPath:
+		return t.match(f.path)
+	case fieldExt:
+		return strings.EqualFold(strings.TrimPrefix(filepath.Ext(f.path), "."), strings.TrimPrefix(t.text, "."))
+	}
+	return f.hits[t.slot]
+}
+
+// query is a compiled search. A nil *query is "no search": every note matches.
+//   - text: the search as typed.
+//   - expr: the parsed expression (nil when only opts.filter is set).
+//   - terms: the content terms, indexed by qterm.slot.
+//   - root: notes dir that path: terms are relative to.
+//   - titles: a title term was seen, so notes must be read for their heading.
+//   - cand: candidate notes of candIx (see noteIndex.candidates), computed
+//     on first use.
+type query struct {
+	text   string
+	opts   searchOpts
+	expr   *qexpr
+	terms  []*qterm
+	root   string
+	titles bool
+	candMu sync.Mutex
+	candIx *noteIndex
+	cand   []bool
+}
+
+// compileQuery parses text (see the syntax above). Blank text yields a nil
+// query unless a filter is set. Matching follows opts (see searchOpts); syntax
+// errors are reported as "invalid query: …" and bad patterns as "invalid regex: …".
+func compileQuery(text string, opts searchOpts) (*query, error) {
+	if strings.TrimSpace(text) == "" {
+		if opts.filter.active() {
+			return &query{opts: opts}, nil
+		}
+		return nil, nil
+	}
+	q := &query{text: text, opts: opts}
+	toks, err := lexQuery(text, opts.regex)
+	p := &queryParser{q: q, toks: toks}
+	if err == nil {
+		q.expr, err = p.parseOr()
+		if err == nil && !p.atEnd() {
+			err = errors.New("unexpected " + toks[p.pos].text)
+		}
+	}
+	var rerr regexError
+	if errors.As(err, &rerr) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid query: %w", err)
+	}
+	if p.paths {
+		if q.root, err = notesRoot(); err != nil {
+			return nil, err
+		}
+	}
+	return q, nil
+}
+
+// String returns the query as typed, or "" for a nil query.
+func (q *query) String() string {
+	if q == nil {
+		return ""
+	}
+	return q.text
+}
+
+// newFacts returns the facts of the note at p with no content hits yet.
+func (q *query) newFacts(p string) *noteFacts {
+	f := &noteFacts{path: filepath.ToSlash(p), hits: make([]bool, len(q.terms))}
+	if q.root != "" {
+		if rel, err := filepath.Rel(q.root, p); err == nil {
+			f.path = filepath.ToSlash(rel)
+		}
+	}
+	return f
+}
+
+// matchLine records the content terms matched by one line of a note and
+// reports whether the line matched any of them.
+func (q *query) matchLine(f *noteFacts, line string) bool {
+	hit := false
+	for i, t := range q.terms {
+		if t.match(line) {
+			f.hits[i], hit = true, true
+		}
+	}
+	return hit
+}
+
+// mayMatch reports whether the indexed note id of ix may satisfy the query,
+// i.e. whether it has to be scanned to find out.
+func (q *query) mayMatch(ix *noteIndex, id int32) bool {
+	q.candMu.Lock()
+	defer q.candMu.Unlock()
+	if q.candIx != ix {
+		q.candIx, q.cand = ix, ix.candidates(q)
+	}
+	return q.cand == nil || q.cand[id]
+}
+
+// matches reports whether a note with the given facts satisfies the query.
+func (q *query) matches(f *noteFacts) bool {
+	return q.expr == nil || q.expr.eval(f)
+}
+
+// keep reports whether the note with info passes the filter of the query,
+// checked before the note is read.
+func (q *query) keep(info fs.FileInfo) bool {
+	return q == nil || q.opts.filter.keep(info)
+}
+
+// nameOnly reports whether a name search can be decided from the note's path
+// alone, without opening the note (no content or title terms).
+func (q *query) nameOnly() bool {
+	return q != nil && q.opts.scope == scopeName && q.expr != nil && len(q.terms) == 0 && !q.titles
+}
+
+// qtoken is a lexical token of the query syntax. Words carry their text;
+// quoted is set for phrases, so "OR" in quotes is a term, not an operator,
+// and quote is the byte offset in text where the quoted part begins.
+type qtoken struct {
+	text   string
+	word   bool
+	quoted bool
+	quote  int
+}
+
+// lexQuery splits text into words, phrases, parentheses and "-" prefixes.
+// Within a word, balanced parentheses are part of the word, so patterns such
+// as foo(bar)? stay intact in regex mode. In regex mode a "(" or "-" directly
+// followed by more of the pattern starts a word too, so (a|b)c and -\d+ are
+// patterns; grouping parentheses must stand alone there ("( a OR b )") and
+// NOT negates.
+func lexQuery(text string, regex bool) ([]qtoken, error) {
+	var toks []qtoken
+	rs := []rune(text)
+	for i := 0; i < len(rs); {
+		r := rs[i]
+		attached := i+1 < len(rs) && !unicode.IsSpace(rs[i+1]) && rs[i+1] != ')'
+		switch {
+		case unicode.IsSpace(r):
+			i++
+			continue
+		case regex && (r == '(' || r == '-') && attached:
+			// part of the pattern; lexed as a word below
+		case r == '(' || r == ')':
+			toks = append(toks, qtoken{text: string(r)})
+			i++
+			continue
+		case r == '-' && attached:
+			toks = append(toks, qtoken{text: "-"})
+			i++
+			continue
+		}
+
+		var b strings.Builder
+		quoted, quote, depth := false, 0, 0
+	word:
+		for ; i < len(rs); i++ {
+			r := rs[i]
+			switch {
+			case r == '\\' && i+1 < len(rs):
+				i++
+				if regex {
+					b.WriteRune('\\')
+				}
+				b.WriteRune(rs[i])
+			case r == '"':
+				// A phrase, possibly after a qualifier (title:"on call").
+				end := i + 1
+				for end < len(rs) && rs[end] != '"' {
+					end++
+				}
+				if end == len(rs) {
+					return nil, errors.New("unterminated quote")
+				}
+				if !quoted {
+					quoted, quote = true, b.Len()
+				}
+				b.WriteString(string(rs[i+1 : end]))
+				i = end
+			case unicode.IsSpace(r):
+				break word
+			case r == '(':
+				depth++
+				b.WriteRune(r)
+			case r == ')':
+				if depth == 0 {
+					break word
+				}
+				depth--
+				b.WriteRune(r)
+			default:
+				b.WriteRune(r)
+			}
+		}
+		if !quoted {
+			quote = b.Len()
+		}
+		toks = append(toks, qtoken{text: b.String(), word: true, quoted: quoted, quote: quote})
+	}
+	return toks, nil
+}
+
+// queryParser is a recursive-descent parser over the tokens of a query:
+//
+//	or    = and { "OR" and }
+//	and   = unary { ["AND"] unary }
+//	unary = ("NOT" | "-") unary | "(" or ")" | term
+type queryParser struct {
+	q     *query
+	toks  []qtoken
+	pos   int
+	paths bool // a path: term was seen (see query.root)
+}
+
+// regexError reports a term that is not a valid pattern in regex mode.
+type regexError struct{ err error }
+
+func (e regexError) Error() string { return "invalid regex: " + e.err.Error() }
+
+// atEnd reports whether every token has been consumed.
+func (p *queryParser) atEnd() bool {
+	return p.pos >= len(p.toks)
+}
+
+// peek returns the next token (check atEnd first), or a zero token.
+func (p *queryParser) peek() qtoken {
+	if p.pos < len(p.toks) {
+		return p.toks[p.pos]
+	}
+	return qtoken{}
+}
+
+// isOp reports whether t is the (unquoted, upper-case) operator op.
+func isOp(t qtoken, op string) bool {
+	return t.word && !t.quoted && t.text == op
+}
+
+func (p *queryParser) parseOr() (*qexpr, error) {
+	e, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for !p.atEnd() && isOp(p.peek(), "OR") {
+		p.pos++
+		rhs, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		e = join(opOr, e, rhs)
+	}
+	return e, nil
+}
+
+func (p *queryParser) parseAnd() (*qexpr, error) {
+	e, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		if p.atEnd() || (!t.word && t.text == ")") || isOp(t, "OR") {
+			return e, nil
+		}
+		if isOp(t, "AND") {
+			p.pos++
+		}
+		rhs, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		e = join(opAnd, e, rhs)
+	}
+}
+
+func (p *queryParser) parseUnary() (*qexpr, error) {
+	t := p.peek()
+	switch {
+	case p.atEnd():
+		return nil, p.expected()
+	case isOp(t, "NOT") || (!t.word && t.text == "-"):
+		p.pos++
+		e, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &qexpr{op: opNot, kids: []*qexpr{e}}, nil
+	case !t.word && t.text == "(":
+		p.pos++
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.atEnd() || p.peek().text != ")" || p.peek().word {
+			return nil, errors.New("missing )")
+		}
+		p.pos++
+		return e, nil
+	case !t.word || isOp(t, "AND") || isOp(t, "OR"):
+		return nil, p.expected()
+	}
+	p.pos++
+	return p.term(t)
+}
+
+// expected describes a missing term at the current position.
+func (p *queryParser) expected() error {
+	if p.pos == 0 {
+		return errors.New("expected a term")
+	}
+	prev := p.toks[p.pos-1].text
+	if !p.atEnd() {
+		return fmt.Errorf("expected a term after %s, got %s", prev, p.peek().text)
+	}
+	return fmt.Errorf("expected a term after %s", prev)
+}
+
+// term builds the expression for a word or phrase token, splitting off a
+// known field qualifier.
+func (p *queryParser) term(t qtoken) (*qexpr, error) {
+	qt := &qterm{field: p.q.opts.scope.field(), text: t.text}
+	if key, val, ok := strings.Cut(t.text, ":"); ok && len(key) < t.quote {
+		if f, known := qualifiers[strings.ToLower(key)]; known {
+			if val == "" && !t.quoted {
+				return nil, fmt.Errorf("empty %s: qualifier", key)
+			}
+			qt.field, qt.text = f, val
+		}
+	}
+	if qt.field != fieldExt {
+		opts := p.q.opts
+		qt.regex, qt.word = opts.regex, opts.word
+		qt.fold = !opts.caseSensitive && !hasUpper(qt.text, opts.regex)
+		pat := regexp.QuoteMeta(qt.text)
+		if opts.regex {
+			// Compile the pattern as typed first so errors quote the user's text.
+			if _, err := regexp.Compile(qt.text); err != nil {
+				return nil, regexError{err}
+			}
+			pat = qt.text
+		}
+		if qt.fold {
+			pat = "(?i)" + pat
+			qt.lower = strings.ToLower(qt.text)
+		}
+		qt.re = regexp.MustCompile(pat)
+	}
+	switch qt.field {
+	case fieldContent:
+		qt.slot = len(p.q.terms)
+		p.q.terms = append(p.q.terms, qt)
+	case fieldTitle:
+		p.q.titles = true
+	case fieldPath:
+		p.paths = true
+	}
+	return &qexpr{op: opTerm, term: qt}, nil
+}
+
+// join combines a and b under op, flattening chains such as a AND b AND c.
+func join(op int, a, b *qexpr) *qexpr {
+	if a.op == op {
+		a.kids = append(a.kids, b)
+		return a
+	}
+	return &qexpr{op: op, kids: []*qexpr{a, b}}
+}
+
+// highlight renders s with the parts matched by the content and title terms
+// of q emphasized (see highlightRunes); s is returned as-is when q is nil.
+func (q *query) highlight(s string, cursor bool) string {
+	if q == nil {
+		return s
+	}
+	return highlightRunes(s, q.matchPositions(s), cursor)
+}
+
+// matchPositions returns the sorted rune offsets of s that lie inside a match
+// of one of the content or title terms of q (and of path terms when searching
+// names, so the matched part of a note's name stands out in the tree).
+func (q *query) matchPositions(s string) []int {
+	rs := []rune(s)
+	covered := make([]bool, len(rs))
+	found := false
+	mark := func(from, to int) {
+		for i := from; i < to; i++ {
+			covered[i], found = true, true
+		}
+	}
+	var walk func(e *qexpr)
+	walk = func(e *qexpr) {
+		if e.op == opNot {
+			return // excluded terms do not occur in matching notes
+		}
+		for _, k := range e.kids {
+			walk(k)
+		}
+		t := e.term
+		if t == nil || t.field == fieldExt || (t.field == fieldPath && q.opts.scope != scopeName) {
+			return
+		}
+		// Byte offsets → rune offsets.
+		for _, loc := range t.find(s, -1) {
+			if loc[0] < loc[1] {
+				mark(utf8.RuneCountInString(s[:loc[0]]), utf8.RuneCountInString(s[:loc[1]]))
+			}
+		}
+	}
+	if q.expr != nil {
+		walk(q.expr)
+	}
+	if !found {
+		return nil
+	}
+	var pos []int
+	for i, c := range covered {
+		if c {
+			pos = append(pos, i)
+		}
+	}
+	return pos
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testNow is the fixed "now" of tests that deal with ages and dates.
+var testNow = time.Date(2026, 10, 16, 12, 0, 0, 0, time.Local)
+
+// testNotes points $HOME (and the XDG dirs nnav uses) at a temp dir and
+// creates the notes dir ~/notes with files, given by slash-separated paths
+// relative to it. Returns the notes dir.
+func testNotes(t *testing.T, files map[string]string) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(home, ".cache"))
+	t.Setenv("XDG_DATA_HOME", filepath.Join(home, ".local", "share"))
+	root := filepath.Join(home, "notes")
+	if err := os.MkdirAll(root, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	for rel, body := range files {
+		p := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(p), 0o700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(body), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+// queryMatches compiles text and evaluates it against a note at rel (relative
+// to the notes dir) with the given title and lines.
+func queryMatches(t *testing.T, text string, opts searchOpts, rel, title string, lines ...string) bool {
+	t.Helper()
+	q, err := compileQuery(text, opts)
+	if err != nil {
+		t.Fatalf("compileQuery(%q): %v", text, err)
+	}
+	root, err := notesRoot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := q.newFacts(filepath.Join(root, filepath.FromSlash(rel)))
+	for _, l := range lines {
+		q.matchLine(f, l)
+	}
+	f.title = title
+	return q.matches(f)
+}
+
+func TestCompileQueryEval(t *testing.T) {
+	testNotes(t, nil)
+	note := []string{"Kubernetes with Helm charts", "a draft for ops"}
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"helm", true},
+		{"HELM", false}, // smart-case: upper case matches case
+		{"helm kubernetes", true},
+		{"helm AND terraform", false},
+		{"helm terraform", false},
+		{"terraform OR helm", true},
+		{"kubernetes AND (terraform OR helm)", true},
+		{"kubernetes (terraform OR ansible)", false},
+		{"helm -draft", false},
+		{"helm NOT draft", false},
+		{"helm -terraform", true},
+		{"NOT NOT helm", true},
+		{`"helm charts"`, true},
+		{`"charts helm"`, false},
+		{"and", false}, // lower-case operators are plain words
+		{"with and", false},
+		{"title:runbook", true},
+		{"title:deploy", false},
+		{"path:ops/", true},
+		{"path:dev/", false},
+		{"ext:md", true},
+		{"ext:.md", true},
+		{"ext:txt", false},
+		{`\-draft`, false}, // escaped: a literal "-draft"
+		{"title:runbook OR terraform", true},
+		{"-title:runbook helm", false},
+	}
+	for _, tt := range tests {
+		if got := queryMatches(t, tt.query, searchOpts{}, "ops/deploy.md", "Runbook", note...); got != tt.want {
+			t.Errorf("%q: got %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestCompileQueryRegex(t *testing.T) {
+	testNotes(t, nil)
+	lines := []string{"TODO(alice): fix the build", "ping 10.0.0.1 first"}
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{`TODO\(\w+\)`, true},
+		{`todo\(\w+\)`, true},
+		{`^fix`, false},
+		{`fix|nothing`, true},
+		{`TODO\(bob\)`, false},
+		{`fix(es)? build`, true},
+		{`(fix|mend) the`, true},
+		{`(fix|mend)the`, false}, // one pattern, not "fix|mend" AND "the"
+		{`(\w+\s){2}build`, true},
+		{`(\d{1,3}\.){3}\d{1,3}`, true},
+		{`^(\d{1,3}\.){3}`, false},
+		{`-\w+`, false},
+		{`NOT -\w+`, true},
+		{`( bob OR alice ) fix`, true},
+		{`( bob OR carol ) fix`, false},
+		{`NOT ( bob OR carol )`, true},
+	}
+	for _, tt := range tests {
+		if got := queryMatches(t, tt.query, searchOpts{regex: true}, "a.md", "", lines...); got != tt.want {
+			t.Errorf("%q: got %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestCompileQueryErrors(t *testing.T) {
+	testNotes(t, nil)
+	tests := []struct {
+		query string
+		opts  searchOpts
+		want  string
+	}{
+		{"(helm", searchOpts{}, "invalid query"},
+		{"helm)", searchOpts{}, "invalid query"},
+		{"helm OR", searchOpts{}, "invalid query"},
+		{"AND helm", searchOpts{}, "invalid query"},
+		{"helm NOT", searchOpts{}, "invalid query"},
+		{"title:", searchOpts{}, "invalid query"},
+		{`"unterminated`, searchOpts{}, "invalid query"},
+		{"[a-", searchOpts{regex: true}, "invalid regex"},
+		{"(a|b", searchOpts{regex: true}, "invalid regex"},
+		{"( a|b", searchOpts{regex: true}, "invalid query"},
+	}
+	for _, tt := range tests {
+		_, err := compileQuery(tt.query, tt.opts)
+		if err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+			t.Errorf("%q: got error %v, want %q…", tt.query, err, tt.want)
+		}
+	}
+}
+
+func TestCompileQueryBlank(t *testing.T) {
+	if q, err := compileQuery("  ", searchOpts{}); q != nil || err != nil {
+		t.Errorf("blank query: got %v, %v; want nil, nil", q, err)
+	}
+	f, err := newNoteFilter("7d", "", "", "", testNow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, err := compileQuery("", searchOpts{filter: f})
+	if err != nil || q == nil || q.expr != nil {
+		t.Fatalf("filter-only query: got %+v, %v", q, err)
+	}
+	if !q.matches(q.newFacts("x.md")) {
+		t.Error("filter-only query must match every note it keeps")
+	}
+}
+
+func TestWordBounded(t *testing.T) {
+	tests := []struct {
+		s        string
+		from, to int
+		want     bool
+	}{
+		{"go", 0, 2, true},
+		{"go home", 0, 2, true},
+		{"good", 0, 2, false},
+		{"ego", 1, 3, false},
+		{"let's go!", 6, 8, true},
+		{"snake_go", 6, 8, false}, // underscore is a word rune
+		{"x-go-y", 2, 4, true},
+		{"café go", 6, 8, true},
+		{"cafégo", 5, 7, false}, // é is a letter
+		{"a -x b", 2, 4, true},  // a match starting with a non-word rune
+		{"a-x", 1, 3, true},
+		{"go", 1, 1, false}, // empty match
+	}
+	for _, tt := range tests {
+		if got := wordBounded(tt.s, tt.from, tt.to); got != tt.want {
+			t.Errorf("wordBounded(%q, %d, %d) = %v, want %v", tt.s, tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestHasUpper(t *testing.T) {
+	tests := []struct {
+		text  string
+		regex bool
+		want  bool
+	}{
+		{"go", false, false},
+		{"Go", false, true},
+		{"ÉTÉ", false, true},
+		{`\W`, false, true}, // plain text: the W is a letter
+		{`\W+go`, true, false},
+		{`\p{Greek}`, true, false},
+		{`\pL\d`, true, false},
+		{`\PLx`, true, false},
+		{`\w+Go`, true, true},
+		{`a\`, true, false},
+	}
+	for _, tt := range tests {
+		if got := hasUpper(tt.text, tt.regex); got != tt.want {
+			t.Errorf("hasUpper(%q, %v) = %v, want %v", tt.text, tt.regex, got, tt.want)
+		}
+	}
+}
+
+func TestSearchCaseAndWord(t *testing.T) {
+	testNotes(t, nil)
+	line := "good stuff in go, Gopher"
+	tests := []struct {
+		query string
+		opts  searchOpts
+		want  bool
+	}{
+		{"go", searchOpts{}, true},
+		{"Go", searchOpts{}, true}, // Gopher
+		{"GO", searchOpts{}, false},
+		{"Good", searchOpts{}, false},
+		{"gopher", searchOpts{caseSensitive: true}, false},
+		{"go", searchOpts{word: true}, true},
+		{"Go", searchOpts{word: true}, false},
+		{"goo", searchOpts{word: true}, false},
+		{"stuff in", searchOpts{word: true}, true},
+		{"tuff", searchOpts{word: true}, false},
+		{`go\w+`, searchOpts{regex: true, word: true}, true},
+		{`Go\w+`, searchOpts{regex: true, caseSensitive: true}, true},
+		{`GO\w+`, searchOpts{regex: true}, false},
+	}
+	for _, tt := range tests {
+		if got := queryMatches(t, tt.query, tt.opts, "a.md", "", line); got != tt.want {
+			t.Errorf("%q %+v: got %v, want %v", tt.query, tt.opts, got, tt.want)
+		}
+	}
+}
+
+func TestMatchPositions(t *testing.T) {
+	tests := []struct {
+		query string
+		opts  searchOpts
+		s     string
+		want  []int
+	}{
+		{"go", searchOpts{}, "Go go", []int{0, 1, 3, 4}},
+		{"Go", searchOpts{}, "good Go", []int{5, 6}},
+		{"go", searchOpts{word: true}, "gogo go", []int{5, 6}},
+		{"é", searchOpts{}, "CAFÉ", []int{3}},
+		{"x -y", searchOpts{}, "x y", []int{0}}, // excluded terms are not marked
+		{"path:x", searchOpts{}, "x", nil},
+		{"x", searchOpts{scope: scopeName}, "x", []int{0}},
+	}
+	testNotes(t, nil)
+	for _, tt := range tests {
+		q, err := compileQuery(tt.query, tt.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := q.matchPositions(tt.s)
+		if len(got) != len(tt.want) {
+			t.Errorf("%q in %q: got %v, want %v", tt.query, tt.s, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%q in %q: got %v, want %v", tt.query, tt.s, got, tt.want)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// resultsHelp is the footer shown while the search results are open.
+const resultsHelp = "↑/↓ next match • <enter> edit at line • <tab> reveal in tree • <esc> close"
+
+// resultsContext is the number of lines shown around every matching line.
+const resultsContext = 2
+
+// resultRow is one row of the results view.
+//   - path: the note the row belongs to.
+//   - line: 1-based line number (0 for note headings and separators).
+//   - text: the rendered row (for note lines, the line number gutter).
+//   - body: the note line itself, highlighted when drawn.
+//   - hit: a matching line; the cursor only stops on these.
+type resultRow struct {
+	path string
+	line int
+	text string
+	body string
+	hit  bool
+}
+
+// results lists every line matching the active search, grouped by note,
+// with resultsContext lines of context around each one.
+//   - rows: headings, context and matching lines, in display order.
+//   - cursor: index into rows, always on a hit (or 0 when there are none).
+//   - notes/hits: counts for the title.
+//   - loading: rows are still being collected (see resultsMsg).
+type results struct {
+	rows    []resultRow
+	cursor  int
+	scroll  int
+	notes   int
+	hits    int
+	loading bool
+}
+
+// resultsMsg delivers the rows collected in the background by openResults.
+type resultsMsg struct {
+	rows  []resultRow
+	notes int
+	hits  int
+}
+
+// openResults opens the results view for the active search and collects the
+// matching lines in the background. Only notes of the filtered tree are
+// scanned (the archive is left out unless it is shown).
+func (m *model) openResults() tea.Cmd {
+	if m.query == nil {
+		m.status = "no active search (press </> first)"
+		return nil
+	}
+	m.results = &results{loading: true}
+	m.status = resultsHelp
+	return m.loadResults()
+}
+
+// loadResults returns the command that scans the notes of the (filtered)
+// tree with the active query, keeping every matching line.
+func (m *model) loadResults() tea.Cmd {
+	var notes []string
+	var collect func(n *Node)
+	collect = func(n *Node) {
+		for _, c := range n.Children {
+			if !m.showArchive && m.archive != "" && inSubtree(c.Path, m.archive) {
+				continue
+			}
+			if c.IsDir {
+				collect(c)
+			} else {
+				notes = append(notes, c.Path)
+			}
+		}
+	}
+	collect(m.root)
+	q, root := m.query, m.root.Path
+	return func() tea.Msg {
+		var msg resultsMsg
+		for _, p := range notes {
+			sc := scanNote(p, q, true)
+			if !sc.Match {
+				continue // changed on disk since the tree was built
+			}
+			msg.notes++
+			msg.hits += len(sc.Hits)
+			msg.rows = append(msg.rows, resultRows(root, p, sc)...)
+		}
+		return msg
+	}
+}
+
+// resultRows renders the rows of one note: a heading with its path and title,
+// then the matching lines with their context. Overlapping context windows are
+// merged and gaps between them are marked with "--", like grep -C.
+func resultRows(root, p string, sc noteScan) []resultRow {
+	rel, err := filepath.Rel(root, p)
+	if err != nil {
+		rel = p
+	}
+	head := rel
+	if t := strings.TrimSpace(sc.Title); t != "" {
+		head += " — " + t
+	}
+	rows := []resultRow{{path: p, text: lipgloss.NewStyle().Bold(true).Render(head)}}
+	if len(sc.Hits) == 0 {
+		// Matched through title:, path: or ext: only.
+		return append(rows, resultRow{path: p, text: "  (no matching lines)"})
+	}
+	width := len(fmt.Sprint(sc.Hits[len(sc.Hits)-1] + 1))
+	hit := make(map[int]bool, len(sc.Hits))
+	for _, n := range sc.Hits {
+		hit[n] = true
+	}
+	last := -1 // last line shown
+	for _, n := range sc.Hits {
+		from := max(n-resultsContext, last+1)
+		to := min(n+resultsContext, len(sc.Lines)-1)
+		if last >= 0 && from > last+1 {
+			rows = append(rows, resultRow{path: p, text: "  --"})
+		}
+		for i := from; i <= to; i++ {
+			sep := "-"
+			if hit[i] {
+				sep = ":"
+			}
+			text := strings.ReplaceAll(sc.Lines[i], "\t", "    ")
+			rows = append(rows, resultRow{
+				path: p,
+				line: i + 1,
+				text: fmt.Sprintf("  %*d%s ", width, i+1, sep),
+				body: text,
+				hit:  hit[i],
+			})
+		}
+		last = max(last, to)
+	}
+	return rows
+}
+
+// fillResults installs the collected rows, keeping the cursor on the same
+// line of the same note when the results are refreshed after an edit.
+func (m *model) fillResults(msg resultsMsg) {
+	r := m.results
+	if r == nil {
+		return // closed in the meantime
+	}
+	var at resultRow
+	if !r.loading && r.cursor < len(r.rows) {
+		at = r.rows[r.cursor]
+	}
+	r.rows, r.notes, r.hits, r.loading = msg.rows, msg.notes, msg.hits, false
+	r.cursor, r.scroll = 0, 0
+	first := -1
+	for i, row := range r.rows {
+		if !row.hit {
+			continue
+		}
+		if first < 0 {
+			first = i
+		}
+		if row.path == at.path && row.line >= at.line {
+			first = i
+			break
+		}
+	}
+	r.cursor = max(first, 0)
+	r.scroll = scrollWindow(r.cursor, 0, len(r.rows), m.height)
+}
+
+// move steps the cursor to the next (dir > 0) or previous (dir < 0) matching
+// line, up to n matches at a time; it stays put when there is none.
+func (r *results) move(dir, n int) {
+	for i := r.cursor + dir; i >= 0 && i < len(r.rows) && n > 0; i += dir {
+		if r.rows[i].hit {
+			r.cursor = i
+			n--
+		}
+	}
+}
+
+// updateResults handles keys while the results view is open.
+func (m model) updateResults(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	r := m.results
+	page := max(1, m.height-4)
+	switch msg.String() {
+	case "esc", "q", "F", "ctrl+c":
+		m.results = nil
+		m.status = helpText
+		return m, nil
+
+	case "down", "j":
+		r.move(1, 1)
+	case "up", "k":
+		r.move(-1, 1)
+	case "pgdown", " ":
+		r.move(1, page/(2*resultsContext+1)+1)
+	case "pgup":
+		r.move(-1, page/(2*resultsContext+1)+1)
+
+	case "enter", "e":
+		if r.cursor < len(r.rows) && r.rows[r.cursor].path != "" {
+			row := r.rows[r.cursor]
+			return m, m.openInEditorAt(row.path, row.line)
+		}
+
+	case "tab":
+		if r.cursor < len(r.rows) && r.rows[r.cursor].path != "" {
+			m.results = nil
+			m.status = helpText
+			m.revealAnywhere(r.rows[r.cursor].path)
+		}
+	}
+	r.scroll = scrollWindow(r.cursor, r.scroll, len(r.rows), m.height)
+	return m, nil
+}
+
+// view renders the results with the query and the match counts as title.
+func (r *results) view(m model) string {
+	title := fmt.Sprintf("results for %s: %d matching lines in %s", m.query.String(), r.hits, countNotes(r.notes))
+	if r.loading {
+		title = "results for " + m.query.String() + " (loading…)"
+	}
+	return m.frame(title, len(r.rows), func(i int) string {
+		return r.rows[i].text + m.query.highlight(r.rows[i].body, i == r.cursor)
+	}, r.cursor, r.scroll)
+}
@@ -34,10 +34,9 @@ func safeJoinWithin(base, userPath string) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	absJoined, err := filepath.EvalSymlinks(joined)
+	absJoined, err := evalExistingPrefix(joined)
 	if err != nil {
-		// If target doesn’t exist yet, fall back to the non-resolved absolute path.
-		absJoined = joined
+		return "", err
 	}
 
 	// Add trailing separator to avoid false prefix matches (e.g., /tmp/foo vs /tmp/foobar).
@@ -50,6 +49,26 @@ func safeJoinWithin(base, userPath string) (string, error) {
 	return absJoined, nil
 }
 
+// evalExistingPrefix resolves symlinks in the longest existing prefix of p and
+// appends the rest unchanged. A path that does not exist yet (a new note, or
+// directories still to be created) is thus checked against where its
+// existing parent really is, so a symlinked parent cannot lead outside base.
+func evalExistingPrefix(p string) (string, error) {
+	rest := ""
+	for {
+		resolved, err := filepath.EvalSymlinks(p)
+		if err == nil {
+			return filepath.Join(resolved, rest), nil
+		}
+		parent := filepath.Dir(p)
+		if parent == p {
+			return "", err
+		}
+		rest = filepath.Join(filepath.Base(p), rest)
+		p = parent
+	}
+}
+
 // safePathWithinNotes ensures a path is located under the notes root directory.
 // Uses safeJoinWithin() to sanitize relative paths. Returns (path, true) if safe,
 // else ("", false). This provides a centralized gatekeeper for file system access.
@@ -109,3 +128,33 @@ func isListableDir(path string) bool {
 	return false
 }
 
+// writeFileAtomic replaces path with data without ever exposing a partially
+// written file: data goes to a temp file in the same directory, is synced to
+// disk and then renamed over path. perm is applied to the resulting file.
+// Callers are responsible for validating path (see safeJoinWithin).
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	// Remove the temp file on any failure; after a successful rename this is a no-op.
+	defer func() { _ = os.Remove(tmp) }()
+
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Chmod(perm); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// savedSearchPrefix starts the config keys of saved searches:
+// "search.<id>=Name|query" in ~/.nnav.
+const savedSearchPrefix = "search."
+
+// savedSearch is a named query from the config, shown as a virtual folder.
+//   - ID: the part of the key after "search." (also orders the folders).
+//   - Name: label shown in the tree (the ID when not given).
+//   - Query: search in the query syntax (see query.go).
+type savedSearch struct {
+	ID    string
+	Name  string
+	Query string
+}
+
+// savedSearches reads the saved searches from ~/.nnav, ordered by ID.
+// Entries with an empty query are skipped.
+func savedSearches() []savedSearch {
+	cfg, _ := loadConfig()
+	var out []savedSearch
+	for k, v := range cfg {
+		id, ok := strings.CutPrefix(k, savedSearchPrefix)
+		if !ok || id == "" {
+			continue
+		}
+		name, query, found := strings.Cut(v, "|")
+		if !found {
+			name, query = "", v
+		}
+		s := savedSearch{ID: id, Name: strings.TrimSpace(name), Query: strings.TrimSpace(query)}
+		if s.Query == "" {
+			continue
+		}
+		if s.Name == "" {
+			s.Name = id
+		}
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// savedSearchNodes returns a collapsed virtual folder for every saved search.
+// Their paths ("search:<id>") never exist on disk; they only key the
+// expansion state kept across reloads (see dirState).
+func savedSearchNodes() []*Node {
+	var nodes []*Node
+	for _, s := range savedSearches() {
+		nodes = append(nodes, &Node{
+			Name:  s.Name,
+			Path:  "search:" + s.ID,
+			IsDir: true,
+			Kind:  kindSearch,
+			Query: s.Query,
+		})
+	}
+	return nodes
+}
+
+// expandSavedSearch runs the query of the virtual folder n over the whole
+// notes dir and lists the matching notes as its children, flattened in tree
+// order. The query runs again on every expansion so the list stays current.
+func expandSavedSearch(n *Node) error {
+	q, err := compileQuery(n.Query, searchOpts{})
+	if err != nil {
+		return fmt.Errorf("saved search %q: %w", n.Name, err)
+	}
+	root, err := notesRoot()
+	if err != nil {
+		return err
+	}
+	nodes, err := readDirNodes(root, q)
+	if err != nil {
+		return err
+	}
+	var notes []*Node
+	var collect func(ns []*Node)
+	collect = func(ns []*Node) {
+		for _, c := range ns {
+			if c.IsDir {
+				collect(c.Children)
+			} else {
+				notes = append(notes, c)
+			}
+		}
+	}
+	collect(nodes)
+	n.Children = notes
+	n.Expanded = true
+	return nil
+}
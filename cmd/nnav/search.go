@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// searchDoneMsg delivers the result of an asynchronous search started by
+// startSearch. seq identifies the search so stale results can be dropped.
+type searchDoneMsg struct {
+	seq   int
+	query *query
+	root  *Node
+	err   error
+}
+
+// searchState remembers how the unfiltered tree looked when a search
+// started, so clearing the search puts the user back where they were.
+//   - expanded: snapshot taken with dirState.
+//   - selected: path under the cursor.
+type searchState struct {
+	expanded map[string]bool
+	selected string
+}
+
+// searchLabel is the prompt label of the "/" input, showing active switches.
+func (m *model) searchLabel() string {
+	var on []string
+	if m.searchOpts.regex {
+		on = append(on, "regex")
+	}
+	if m.searchOpts.caseSensitive {
+		on = append(on, "case")
+	}
+	if m.searchOpts.word {
+		on = append(on, "word")
+	}
+	if sc := m.searchOpts.scope; sc != scopeContent {
+		on = append(on, "in:"+sc.String())
+	}
+	if len(on) == 0 {
+		return "/"
+	}
+	return "[" + strings.Join(on, " ") + "] /"
+}
+
+// openSearch opens the "/" input. The tree is filtered while typing; <enter>
+// keeps the filter, <esc> or an empty query restores the unfiltered tree.
+// <alt+r>, <alt+c> and <alt+w> toggle regex, case-sensitive and whole-word
+// matching and <alt+s> cycles the search scope, without leaving the input.
+func (m *model) openSearch() {
+	m.saveTreeState()
+	m.ask(m.searchLabel(), m.query.String(), func(m *model, text string) tea.Cmd {
+		if strings.TrimSpace(text) == "" {
+			m.clearSearch()
+			return nil
+		}
+		if _, err := compileQuery(text, m.searchOpts); err != nil {
+			m.status = err.Error()
+			return nil
+		}
+		m.status = "search: " + text + " • </> change • <esc> clear"
+		return nil
+	})
+	m.prompt.onChange = func(m *model, text string) tea.Cmd {
+		return m.startSearch(text)
+	}
+	m.prompt.onCancel = func(m *model) {
+		m.clearSearch()
+		m.status = helpText
+	}
+	m.prompt.keys = map[string]func(m *model) tea.Cmd{
+		"alt+r": func(m *model) tea.Cmd { return m.toggleSearchOpt("regex", &m.searchOpts.regex) },
+		"alt+c": func(m *model) tea.Cmd { return m.toggleSearchOpt("case-sensitive", &m.searchOpts.caseSensitive) },
+		"alt+w": func(m *model) tea.Cmd { return m.toggleSearchOpt("whole-word", &m.searchOpts.word) },
+		"alt+s": func(m *model) tea.Cmd { return m.cycleSearchScope() },
+	}
+}
+
+// saveTreeState remembers the unfiltered tree's expansion state and
+// selection before a search or filter is applied (see clearSearch).
+func (m *model) saveTreeState() {
+	if m.query != nil && m.preSearch != nil {
+		return // already filtered: keep the state from before the first filter
+	}
+	st := &searchState{expanded: map[string]bool{}}
+	dirState(m.root, st.expanded)
+	if cur := m.selected(); cur != nil {
+		st.selected = cur.Path
+	}
+	m.preSearch = st
+}
+
+// toggleSearchOpt flips one of the search switches and re-runs the search
+// being typed (or the active one) with the new setting.
+func (m *model) toggleSearchOpt(name string, opt *bool) tea.Cmd {
+	*opt = !*opt
+	state := "off"
+	if *opt {
+		state = "on"
+	}
+	m.status = name + " " + state
+	return m.rerunSearch()
+}
+
+// cycleSearchScope switches unqualified terms to the next scope (content →
+// title → name → content) and re-runs the search like toggleSearchOpt.
+func (m *model) cycleSearchScope() tea.Cmd {
+	m.searchOpts.scope = (m.searchOpts.scope + 1) % searchScope(len(scopeNames))
+	m.status = "search in: " + m.searchOpts.scope.String()
+	return m.rerunSearch()
+}
+
+// rerunSearch re-runs the search being typed (or the active one) after a
+// search switch changed.
+func (m *model) rerunSearch() tea.Cmd {
+	if m.prompt != nil && m.prompt.onChange != nil {
+		m.prompt.label = m.searchLabel()
+		m.prompt.hint = ""
+		return m.startSearch(m.prompt.value)
+	}
+	if m.query != nil {
+		return m.startSearch(m.query.text)
+	}
+	return nil
+}
+
+// startSearch cancels the search in flight (if any) and returns a command
+// that builds the filtered tree in the background. An empty text restores
+// the unfiltered tree right away; an invalid pattern is reported next to the
+// input (and in the status) and leaves the current tree alone.
+func (m *model) startSearch(text string) tea.Cmd {
+	if m.searchCancel != nil {
+		m.searchCancel()
+		m.searchCancel = nil
+	}
+	m.searchSeq++
+	q, err := compileQuery(text, m.searchOpts)
+	if m.prompt != nil {
+		m.prompt.hint = ""
+		if err != nil {
+			m.prompt.hint = err.Error()
+		}
+	}
+	if err != nil {
+		m.status = err.Error()
+		return nil
+	}
+	if q == nil {
+		m.clearSearch()
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.searchCancel = cancel
+	seq, rootPath := m.searchSeq, m.root.Path
+	return func() tea.Msg {
+		root, err := buildTreeCtx(ctx, rootPath, q)
+		return searchDoneMsg{seq: seq, query: q, root: root, err: err}
+	}
+}
+
+// applySearch shows the tree of a finished search unless a newer search has
+// been started since.
+func (m *model) applySearch(msg searchDoneMsg) {
+	if msg.seq != m.searchSeq {
+		return // superseded by a later keystroke
+	}
+	m.searchCancel = nil
+	if msg.err != nil {
+		if !errors.Is(msg.err, context.Canceled) {
+			m.status = "search failed: " + msg.err.Error()
+		}
+		return
+	}
+	m.root = msg.root
+	m.query = msg.query
+	m.cursor, m.scroll = 0, 0
+	m.recompute()
+}
+
+// clearSearch drops the search text. With a date/size filter still set, the
+// tree is rebuilt with just the filter; otherwise the full tree is rebuilt
+// and the expansion state and selection from before the search are restored.
+func (m *model) clearSearch() {
+	if m.searchCancel != nil {
+		m.searchCancel()
+		m.searchCancel = nil
+	}
+	m.searchSeq++
+	if m.query == nil {
+		m.preSearch = nil
+		return
+	}
+	if m.searchOpts.filter.active() {
+		q, _ := compileQuery("", m.searchOpts)
+		root, err := buildTree(m.root.Path, q)
+		if err != nil {
+			m.status = "reload failed: " + err.Error()
+			return
+		}
+		m.root, m.query = root, q
+		m.cursor, m.scroll = 0, 0
+		m.recompute()
+		return
+	}
+	st := m.preSearch
+	m.preSearch = nil
+	m.query = nil
+	root, err := buildTree(m.root.Path, nil)
+	if err != nil {
+		m.status = "reload failed: " + err.Error()
+		return
+	}
+	m.root = root
+	m.cursor, m.scroll = 0, 0
+	if st != nil {
+		restoreDirState(root, st.expanded, nil)
+	}
+	m.recompute()
+	if st != nil && st.selected != "" {
+		m.reveal(st.selected)
+	}
+}
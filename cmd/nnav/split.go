@@ -0,0 +1,283 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// notePart is a note that a split or merge is about to write.
+type notePart struct {
+	Name string // file name inside the target directory
+	Body string
+}
+
+// splitPlan describes how a note is broken up by splitNote before anything
+// is written, so it can be previewed.
+//   - Src: the note being split.
+//   - Before: the content of Src the plan was made from.
+//   - Rest: the new content of Src (its text outside the split sections,
+//     with links to the new notes in place of the sections).
+//   - Parts: one new note per heading of the chosen level.
+type splitPlan struct {
+	Src    string
+	Before string
+	Rest   string
+	Parts  []notePart
+}
+
+// headingLevel returns the Markdown level (1-6) of a line matched by
+// headingRE, i.e. the number of leading '#' characters.
+func headingLevel(line string) int {
+	t := strings.TrimLeft(line, " \t")
+	return len(t) - len(strings.TrimLeft(t, "#"))
+}
+
+// slugify turns a title into a file-name friendly slug: lowercase letters and
+// digits separated by single dashes ("Q3 Planning: Goals" → "q3-planning-goals").
+func slugify(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+		dash = true
+	}
+	slug := b.String()
+	if r := []rune(slug); len(r) > 60 {
+		slug = strings.TrimRight(string(r[:60]), "-")
+	}
+	return slug
+}
+
+// uniqueName returns stem+ext, or stem-2+ext, stem-3+ext, … so that the name
+// is neither taken in dir on disk nor already in taken. The chosen name is
+// added to taken.
+func uniqueName(dir, stem, ext string, taken map[string]bool) string {
+	for i := 1; ; i++ {
+		name := stem + ext
+		if i > 1 {
+			name = fmt.Sprintf("%s-%d%s", stem, i, ext)
+		}
+		if taken[name] {
+			continue
+		}
+		if _, err := os.Lstat(filepath.Join(dir, name)); err == nil {
+			continue
+		}
+		taken[name] = true
+		return name
+	}
+}
+
+// fenceRE matches a line opening or closing a fenced code block: three or
+// more backticks or tildes, indented by at most three spaces.
+var fenceRE = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})(.*)$")
+
+// nextFence returns the marker of the code fence open after line, given the
+// one open before it ("" outside code). Like CommonMark, a fence is only
+// closed by a bare run of the same character at least as long as its opener,
+// so ``` inside a ~~~ block does not end it.
+func nextFence(open, line string) string {
+	m := fenceRE.FindStringSubmatch(line)
+	switch {
+	case m == nil:
+		return open
+	case open == "":
+		if m[1][0] == '`' && strings.Contains(m[2], "`") {
+			return "" // not a fence: backtick info strings cannot contain backticks
+		}
+		return m[1]
+	case m[1][0] == open[0] && len(m[1]) >= len(open) && strings.TrimSpace(m[2]) == "":
+		return ""
+	}
+	return open
+}
+
+// planSplit reads the note at p and plans one new note per heading of the
+// given level, using the same headingRE as scanTitle. A section runs up to
+// the next heading of the same or a higher level, so deeper headings stay in
+// it and higher ones (with the text below them) stay in p; headings inside
+// fenced code blocks (``` or ~~~) are ignored. New notes are named after the
+// slug of their heading and never collide with existing files.
+func planSplit(p string, level int) (*splitPlan, error) {
+	if level < 1 || level > 6 {
+		return nil, errors.New("heading level must be between 1 and 6")
+	}
+	text, err := readNote(p)
+	if err != nil {
+		return nil, err
+	}
+
+	// The note as a sequence of chunks: text kept in p, or a section that
+	// becomes a new note (and a link in p).
+	type chunk struct {
+		title string // "" for kept text
+		lines []string
+	}
+	var chunks []*chunk
+	var cur *chunk
+	fence := "" // marker of the open code fence, if any
+
+	s := bufio.NewScanner(strings.NewReader(text))
+	s.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for s.Scan() {
+		line := s.Text()
+		fence = nextFence(fence, line)
+		if fence == "" {
+			if m := headingRE.FindStringSubmatch(line); m != nil && headingLevel(line) <= level {
+				cur = nil
+				if headingLevel(line) == level {
+					cur = &chunk{title: m[1]}
+					chunks = append(chunks, cur)
+				}
+			}
+		}
+		if cur == nil {
+			cur = &chunk{}
+			chunks = append(chunks, cur)
+		}
+		cur.lines = append(cur.lines, line)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Dir(p)
+	ext := filepath.Ext(p)
+	taken := map[string]bool{filepath.Base(p): true}
+	plan := &splitPlan{Src: p, Before: text}
+	var rest, links []string
+	for _, c := range chunks {
+		if c.title == "" {
+			if len(links) > 0 {
+				rest, links = append(rest, strings.Join(links, "\n")), nil
+			}
+			if kept := strings.Trim(strings.Join(c.lines, "\n"), "\n"); kept != "" {
+				rest = append(rest, kept)
+			}
+			continue
+		}
+		stem := slugify(c.title)
+		if stem == "" {
+			stem = "section"
+		}
+		name := uniqueName(dir, stem, ext, taken)
+		plan.Parts = append(plan.Parts, notePart{Name: name, Body: strings.Join(c.lines, "\n") + "\n"})
+		if ext == ".md" {
+			links = append(links, "- ["+c.title+"]("+name+")")
+		} else {
+			links = append(links, "- "+c.title+": "+name)
+		}
+	}
+	if len(plan.Parts) == 0 {
+		return nil, fmt.Errorf("no level-%d headings in %s", level, filepath.Base(p))
+	}
+	if len(links) > 0 {
+		rest = append(rest, strings.Join(links, "\n"))
+	}
+	plan.Rest = strings.Join(rest, "\n\n") + "\n"
+	return plan, nil
+}
+
+// preview renders the plan for the pager.
+func (sp *splitPlan) preview() []string {
+	lines := []string{fmt.Sprintf("split %s into %d notes:", filepath.Base(sp.Src), len(sp.Parts)), ""}
+	for _, part := range sp.Parts {
+		n := strings.Count(part.Body, "\n")
+		lines = append(lines, fmt.Sprintf("  + %s (%d lines)", part.Name, n))
+	}
+	lines = append(lines, "", filepath.Base(sp.Src)+" will contain:", "")
+	for _, l := range strings.Split(strings.TrimRight(sp.Rest, "\n"), "\n") {
+		lines = append(lines, "  "+l)
+	}
+	return lines
+}
+
+// apply writes the new notes and rewrites the source. It returns the ops
+// performed (for the undo log), including those of a partially applied plan.
+// Nothing is written if the source changed since the plan was made.
+func (sp *splitPlan) apply() ([]fileOp, error) {
+	cur, err := readNote(sp.Src)
+	if err != nil {
+		return nil, err
+	}
+	if cur != sp.Before {
+		return nil, fmt.Errorf("%s was modified since the preview", filepath.Base(sp.Src))
+	}
+	var ops []fileOp
+	dir := filepath.Dir(sp.Src)
+	for _, part := range sp.Parts {
+		p, err := writeNewNote(dir, part.Name, part.Body)
+		if err != nil {
+			return ops, err
+		}
+		ops = append(ops, fileOp{Kind: opCreate, To: p})
+	}
+	if err := rewriteNote(sp.Src, sp.Before, sp.Rest); err != nil {
+		return ops, err
+	}
+	return append(ops, fileOp{Kind: opEdit, To: sp.Src, Before: sp.Before, After: sp.Rest}), nil
+}
+
+// planMerge concatenates the notes in paths (in order) into the body of a new
+// note, starting each source with a level-1 heading named after its title
+// (or file name). A source's own leading title heading is dropped so it is
+// not repeated under the new heading.
+func planMerge(paths []string) (string, error) {
+	var b strings.Builder
+	for i, p := range paths {
+		text, err := readNote(p)
+		if err != nil {
+			return "", err
+		}
+		title, _ := scanTitle(p, nil)
+		heading := strings.TrimSpace(title)
+		if heading == "" {
+			heading = strings.TrimSuffix(filepath.Base(p), filepath.Ext(p))
+		}
+		lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+		for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+			lines = lines[1:]
+		}
+		if len(lines) > 0 && title != "" {
+			if m := headingRE.FindStringSubmatch(lines[0]); m != nil && m[1] == title {
+				lines = lines[1:]
+			}
+		}
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString("# " + heading + "\n")
+		body := strings.Trim(strings.Join(lines, "\n"), "\n")
+		if body != "" {
+			b.WriteString("\n" + body + "\n")
+		}
+	}
+	return b.String(), nil
+}
+
+// readNote returns the contents of a note inside the notes root.
+func readNote(p string) (string, error) {
+	safe, ok := safePathWithinNotes(p)
+	if !ok {
+		return "", fmt.Errorf("path outside notes dir: %s", p)
+	}
+	// #nosec G304 -- safe is validated by safePathWithinNotes.
+	data, err := os.ReadFile(safe)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
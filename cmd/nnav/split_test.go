@@ -0,0 +1,185 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNextFence(t *testing.T) {
+	tests := []struct {
+		open, line, want string
+	}{
+		{"", "plain text", ""},
+		{"", "```", "```"},
+		{"", "```go", "```"},
+		{"", "   ~~~~ sh", "~~~~"},
+		{"", "    ```", ""}, // indented code, not a fence
+		{"", "``` a`b", ""},
+		{"", "~~~ a`b", "~~~"},
+		{"```", "```", ""},
+		{"```", "````", ""},
+		{"````", "```", "````"},
+		{"```", "``` go", "```"},
+		{"```", "~~~", "```"},
+		{"~~~", "```", "~~~"},
+		{"~~~", "~~~  ", ""},
+	}
+	for _, tt := range tests {
+		if got := nextFence(tt.open, tt.line); got != tt.want {
+			t.Errorf("nextFence(%q, %q) = %q, want %q", tt.open, tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestPlanSplit(t *testing.T) {
+	tests := []struct {
+		name  string
+		note  string // written to big.md
+		level int
+		parts []string // "name: body"
+		rest  string
+		err   string
+	}{
+		{
+			name:  "level 2 sections",
+			note:  "# Big\n\nintro\n\n## One\n\nfirst\n### Deeper\nstill one\n## Two\nsecond\n",
+			level: 2,
+			parts: []string{"one.md: ## One\n\nfirst\n### Deeper\nstill one\n", "two.md: ## Two\nsecond\n"},
+			rest:  "# Big\n\nintro\n\n- [One](one.md)\n- [Two](two.md)\n",
+		},
+		{
+			name:  "headings in fences",
+			note:  "## A\n```sh\n## not a heading\n```\n~~~\n```\n## still code\n~~~\n## B\n",
+			level: 2,
+			parts: []string{"a.md: ## A\n```sh\n## not a heading\n```\n~~~\n```\n## still code\n~~~\n", "b.md: ## B\n"},
+			rest:  "- [A](a.md)\n- [B](b.md)\n",
+		},
+		{
+			name:  "names never collide",
+			note:  "# Taken\nx\n# Same\ny\n# Same\nz\n# !!!\nw\n",
+			level: 1,
+			parts: []string{"taken-2.md: # Taken\nx\n", "same.md: # Same\ny\n", "same-2.md: # Same\nz\n", "section.md: # !!!\nw\n"},
+			rest:  "- [Taken](taken-2.md)\n- [Same](same.md)\n- [Same](same-2.md)\n- [!!!](section.md)\n",
+		},
+		{
+			name:  "higher headings end a section",
+			note:  "intro\n## A\na\n# Part two\n\np\n## B\nb\n### B.1\nb1\n# Appendix\nz\n",
+			level: 2,
+			parts: []string{"a.md: ## A\na\n", "b.md: ## B\nb\n### B.1\nb1\n"},
+			rest:  "intro\n\n- [A](a.md)\n\n# Part two\n\np\n\n- [B](b.md)\n\n# Appendix\nz\n",
+		},
+		{
+			name:  "no headings",
+			note:  "# Big\ntext\n",
+			level: 3,
+			err:   "no level-3 headings in big.md",
+		},
+		{
+			name:  "bad level",
+			note:  "# Big\n",
+			level: 7,
+			err:   "heading level must be between 1 and 6",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := testNotes(t, map[string]string{"big.md": tt.note, "taken.md": ""})
+			plan, err := planSplit(filepath.Join(root, "big.md"), tt.level)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("planSplit error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var parts []string
+			for _, p := range plan.Parts {
+				parts = append(parts, p.Name+": "+p.Body)
+			}
+			if strings.Join(parts, "|") != strings.Join(tt.parts, "|") {
+				t.Errorf("parts = %q, want %q", parts, tt.parts)
+			}
+			if plan.Rest != tt.rest {
+				t.Errorf("rest = %q, want %q", plan.Rest, tt.rest)
+			}
+		})
+	}
+}
+
+func TestPlanSplitText(t *testing.T) {
+	root := testNotes(t, map[string]string{"log.txt": "# Mon\na\n# Tue\nb\n"})
+	plan, err := planSplit(filepath.Join(root, "log.txt"), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Parts[0].Name != "mon.txt" || plan.Rest != "- Mon: mon.txt\n- Tue: tue.txt\n" {
+		t.Errorf("plan = %+v", plan)
+	}
+}
+
+func TestSplitApply(t *testing.T) {
+	root := testNotes(t, map[string]string{"big.md": "# Big\n## A\na\n## B\nb\n"})
+	big := filepath.Join(root, "big.md")
+	plan, err := planSplit(big, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An edit made after the preview must not be overwritten.
+	if err := os.WriteFile(big, []byte("# Big\n## A\na, edited\n## B\nb\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if ops, err := plan.apply(); err == nil || len(ops) != 0 {
+		t.Fatalf("apply after an edit = %v, %v; want an error and no ops", ops, err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "a.md")); !os.IsNotExist(err) {
+		t.Error("apply after an edit wrote a.md")
+	}
+
+	if plan, err = planSplit(big, 2); err != nil {
+		t.Fatal(err)
+	}
+	ops, err := plan.apply()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ops) != 3 || ops[2].Kind != opEdit || ops[2].Before != plan.Before || ops[2].After != plan.Rest {
+		t.Errorf("ops = %+v", ops)
+	}
+	if got := readTestNote(t, big); got != "# Big\n\n- [A](a.md)\n- [B](b.md)\n" {
+		t.Errorf("big.md = %q", got)
+	}
+	if got := readTestNote(t, filepath.Join(root, "a.md")); got != "## A\na, edited\n" {
+		t.Errorf("a.md = %q", got)
+	}
+}
+
+func TestPlanMerge(t *testing.T) {
+	root := testNotes(t, map[string]string{
+		"a.md":     "# Alpha\n\nfirst note\n",
+		"b.txt":    "\n\nno heading here\n\n",
+		"c.md":     "## Gamma\nbody\n# Other\nmore\n",
+		"empty.md": "",
+	})
+	p := func(name string) string { return filepath.Join(root, name) }
+
+	got, err := planMerge([]string{p("a.md"), p("b.txt"), p("c.md"), p("empty.md")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "# Alpha\n\nfirst note\n" +
+		"\n# b\n\nno heading here\n" +
+		"\n# Gamma\n\nbody\n# Other\nmore\n" +
+		"\n# empty\n"
+	if got != want {
+		t.Errorf("planMerge =\n%s\nwant\n%s", got, want)
+	}
+
+	if _, err := planMerge([]string{p("a.md"), p("missing.md")}); err == nil {
+		t.Error("planMerge of a missing note succeeded")
+	}
+}
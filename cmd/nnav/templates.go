@@ -0,0 +1,122 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// defaultTemplatesSubdir is used under the notes root when "templatesdir" is unset.
+const defaultTemplatesSubdir = ".templates"
+
+// placeholderRE matches template placeholders such as {{title}} or {{ date }}.
+var placeholderRE = regexp.MustCompile(`\{\{\s*(\w+)\s*\}\}`)
+
+// templatesDir returns the configured templates directory.
+// Priority: (1) "templatesdir" in ~/.nnav (tilde-expanded), else (2) <notesdir>/.templates.
+func templatesDir() (string, error) {
+	cfg, _ := loadConfig()
+	if v := strings.TrimSpace(cfg["templatesdir"]); v != "" {
+		dir, err := expandTilde(v)
+		if err != nil {
+			return "", err
+		}
+		return filepath.Clean(dir), nil
+	}
+	root, err := notesRoot()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(root, defaultTemplatesSubdir), nil
+}
+
+// listTemplates returns the template file names (allowed extensions only),
+// sorted case-insensitively. A missing templates directory is not an error.
+func listTemplates() ([]string, error) {
+	dir, err := templatesDir()
+	if err != nil {
+		return nil, err
+	}
+	ents, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range ents {
+		if e.IsDir() || !allowedExts[strings.ToLower(filepath.Ext(e.Name()))] {
+			continue
+		}
+		names = append(names, e.Name())
+	}
+	sort.Slice(names, func(i, j int) bool { return strings.ToLower(names[i]) < strings.ToLower(names[j]) })
+	return names, nil
+}
+
+// readTemplate loads the template called name from the templates directory.
+// Only bare file names are accepted so a template name cannot escape the dir.
+func readTemplate(name string) (string, error) {
+	if err := validateEntryName(name); err != nil {
+		return "", err
+	}
+	dir, err := templatesDir()
+	if err != nil {
+		return "", err
+	}
+	// #nosec G304 -- name is a validated bare file name inside the templates dir.
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// templateVars returns the placeholder values for a note about to be created
+// at path p:
+//   - title: the given title, else the file name without extension (and
+//     without a Zettelkasten ID prefix)
+//   - date/time: current local date (2006-01-02) and time (15:04)
+//   - dir: directory of the note relative to the notes root ("" at the root)
+//   - user: current user name
+func templateVars(p, title string, now time.Time) map[string]string {
+	dir := ""
+	if root, err := notesRoot(); err == nil {
+		if rel, err := filepath.Rel(root, filepath.Dir(p)); err == nil && rel != "." {
+			dir = rel
+		}
+	}
+	name := os.Getenv("USER")
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	if title == "" {
+		base := filepath.Base(p)
+		_, title = splitNoteID(strings.TrimSuffix(base, filepath.Ext(base)))
+	}
+	return map[string]string{
+		"title": title,
+		"date":  now.Format("2006-01-02"),
+		"time":  now.Format("15:04"),
+		"dir":   dir,
+		"user":  name,
+	}
+}
+
+// renderTemplate substitutes {{name}} placeholders with vars.
+// Unknown placeholders are left untouched so typos stay visible in the note.
+func renderTemplate(text string, vars map[string]string) string {
+	return placeholderRE.ReplaceAllStringFunc(text, func(ph string) string {
+		key := strings.ToLower(placeholderRE.FindStringSubmatch(ph)[1])
+		if v, ok := vars[key]; ok {
+			return v
+		}
+		return ph
+	})
+}
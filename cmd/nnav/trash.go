@@ -0,0 +1,258 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// notesTrashDir is the trash directory created under the notes root when
+// trash=notes (the default). It is hidden from the tree by readDirNodes.
+const notesTrashDir = ".nnav-trash"
+
+// trashInfoTime is the DeletionDate layout required by the freedesktop Trash spec.
+const trashInfoTime = "2006-01-02T15:04:05"
+
+// trashEntry is one trashed note.
+//   - Name: file name inside the trash "files" directory.
+//   - OrigPath: absolute path the note was deleted from (restore target).
+//   - Deleted: deletion time recorded in the .trashinfo file.
+type trashEntry struct {
+	Name     string
+	OrigPath string
+	Deleted  time.Time
+}
+
+// trashDir returns the trash root according to the "trash" config key.
+//   - "notes" (default): <notesdir>/.nnav-trash, always on the same filesystem
+//     as the notes so trashing is a cheap, atomic rename.
+//   - "freedesktop": the user's home trash ($XDG_DATA_HOME/Trash), shared with
+//     desktop file managers.
+//
+// Both layouts follow the freedesktop spec: files/ holds the trashed entries
+// and info/<name>.trashinfo records where each one came from.
+func trashDir() (string, error) {
+	cfg, _ := loadConfig()
+	switch mode := strings.ToLower(strings.TrimSpace(cfg["trash"])); mode {
+	case "", "notes":
+		root, err := notesRoot()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(root, notesTrashDir), nil
+	case "freedesktop":
+		data := os.Getenv("XDG_DATA_HOME")
+		if data == "" || !filepath.IsAbs(data) {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return "", err
+			}
+			data = filepath.Join(home, ".local", "share")
+		}
+		return filepath.Join(data, "Trash"), nil
+	default:
+		return "", fmt.Errorf("invalid trash setting: %q (use notes or freedesktop)", mode)
+	}
+}
+
+// trashNote moves the note at p into the trash and records its origin.
+// The .trashinfo file is created exclusively first, which reserves a unique
+// name in the trash; if the move then fails the reservation is rolled back.
+func trashNote(p string) (trashEntry, error) {
+	src, err := safeEntry(p)
+	if err != nil {
+		return trashEntry{}, err
+	}
+	dir, err := trashDir()
+	if err != nil {
+		return trashEntry{}, err
+	}
+	for _, sub := range []string{"files", "info"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o700); err != nil {
+			return trashEntry{}, err
+		}
+	}
+
+	e := trashEntry{OrigPath: p, Deleted: time.Now()}
+	base := filepath.Base(p)
+	ext := filepath.Ext(base)
+	stem := strings.TrimSuffix(base, ext)
+	for i := 1; ; i++ {
+		e.Name = base
+		if i > 1 {
+			e.Name = fmt.Sprintf("%s.%d%s", stem, i, ext)
+		}
+		info := filepath.Join(dir, "info", e.Name+".trashinfo")
+		// #nosec G304 -- info is built from the trash dir and a bare file name.
+		f, err := os.OpenFile(info, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return trashEntry{}, err
+		}
+		_, werr := fmt.Fprintf(f, "[Trash Info]\nPath=%s\nDeletionDate=%s\n",
+			(&url.URL{Path: e.OrigPath}).EscapedPath(), e.Deleted.Format(trashInfoTime))
+		if cerr := f.Close(); werr == nil {
+			werr = cerr
+		}
+		if werr == nil {
+			werr = renameNoReplace(src, filepath.Join(dir, "files", e.Name))
+		}
+		if werr != nil {
+			_ = os.Remove(info)
+			if errors.Is(werr, os.ErrExist) {
+				continue // stray file without info: try the next name
+			}
+			if errors.Is(werr, syscall.EXDEV) {
+				return trashEntry{}, errors.New("trash is on another filesystem (set trash=notes in ~/.nnav)")
+			}
+			return trashEntry{}, werr
+		}
+		return e, nil
+	}
+}
+
+// listTrash returns the trashed notes that came from the notes root, newest first.
+// Entries from other applications (freedesktop trash) and unparsable info
+// files are skipped.
+func listTrash() ([]trashEntry, error) {
+	dir, err := trashDir()
+	if err != nil {
+		return nil, err
+	}
+	root, err := notesRoot()
+	if err != nil {
+		return nil, err
+	}
+	infos, err := os.ReadDir(filepath.Join(dir, "info"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var out []trashEntry
+	for _, ie := range infos {
+		name, ok := strings.CutSuffix(ie.Name(), ".trashinfo")
+		if !ok {
+			continue
+		}
+		e, err := readTrashInfo(filepath.Join(dir, "info", ie.Name()))
+		if err != nil {
+			continue
+		}
+		if !fromNotesDir(root, e.OrigPath) {
+			continue
+		}
+		e.Name = name
+		out = append(out, e)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Deleted.After(out[j].Deleted) })
+	return out, nil
+}
+
+// fromNotesDir reports whether orig, the origin of a trashed note, lies below
+// the notes dir root. Paths are compared cleaned and, failing that, with
+// symlinks resolved, so a trailing slash in notesdir or a symlinked notes dir
+// still match.
+func fromNotesDir(root, orig string) bool {
+	root, orig = filepath.Clean(root), filepath.Clean(orig)
+	if orig != root && inSubtree(orig, root) {
+		return true
+	}
+	r, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return false
+	}
+	o, err := evalExistingPrefix(orig)
+	return err == nil && o != r && inSubtree(o, r)
+}
+
+// readTrashInfo parses the Path and DeletionDate keys of a .trashinfo file.
+func readTrashInfo(p string) (trashEntry, error) {
+	// #nosec G304 -- p is an entry of the trash info directory.
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return trashEntry{}, err
+	}
+	var e trashEntry
+	for _, line := range strings.Split(string(data), "\n") {
+		k, v, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok {
+			continue
+		}
+		switch k {
+		case "Path":
+			if e.OrigPath, err = url.PathUnescape(v); err != nil {
+				return trashEntry{}, err
+			}
+		case "DeletionDate":
+			e.Deleted, _ = time.ParseInLocation(trashInfoTime, v, time.Local)
+		}
+	}
+	if !filepath.IsAbs(e.OrigPath) {
+		return trashEntry{}, fmt.Errorf("no absolute Path in %s", p)
+	}
+	return e, nil
+}
+
+// restoreTrash moves a trashed note back to where it came from and returns
+// that path. Missing parent directories are recreated; an existing note at
+// the original location is never overwritten.
+func restoreTrash(e trashEntry) (string, error) {
+	dir, err := trashDir()
+	if err != nil {
+		return "", err
+	}
+	if err := validateEntryName(e.Name); err != nil {
+		return "", err
+	}
+	root, err := notesRoot()
+	if err != nil {
+		return "", err
+	}
+	parent := filepath.Dir(e.OrigPath)
+	rel, err := filepath.Rel(root, parent)
+	if err != nil {
+		return "", err
+	}
+	safeParent, err := safeJoinWithin(root, rel)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(safeParent, 0o700); err != nil {
+		return "", err
+	}
+	logical, dst, err := safeTarget(parent, filepath.Base(e.OrigPath))
+	if err != nil {
+		return "", err
+	}
+	if err := renameNoReplace(filepath.Join(dir, "files", e.Name), dst); err != nil {
+		return "", err
+	}
+	_ = os.Remove(filepath.Join(dir, "info", e.Name+".trashinfo"))
+	return logical, nil
+}
+
+// purgeTrash permanently deletes a trashed note and its info file.
+func purgeTrash(e trashEntry) error {
+	dir, err := trashDir()
+	if err != nil {
+		return err
+	}
+	if err := validateEntryName(e.Name); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(filepath.Join(dir, "files", e.Name)); err != nil {
+		return err
+	}
+	return os.Remove(filepath.Join(dir, "info", e.Name+".trashinfo"))
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// trashHelp is the footer shown while the trash view is open.
+const trashHelp = "↑/↓ move • <r> restore • <D> delete forever • <esc> back"
+
+// trashView lists trashed notes so they can be restored or purged for good.
+type trashView struct {
+	entries []trashEntry
+	cursor  int
+	scroll  int
+}
+
+// openTrash loads the trash listing and switches to the trash view.
+func (m *model) openTrash() {
+	entries, err := listTrash()
+	if err != nil {
+		m.status = "trash error: " + err.Error()
+		return
+	}
+	m.trash = &trashView{entries: entries}
+	m.status = trashHelp
+	if len(entries) == 0 {
+		m.status = "trash is empty • <esc> back"
+	}
+}
+
+// updateTrash handles keys while the trash view is open.
+func (m model) updateTrash(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	t := m.trash
+	switch msg.String() {
+	case "esc", "q", "X", "ctrl+c":
+		m.trash = nil
+		m.status = helpText
+
+	case "down", "j":
+		if t.cursor < len(t.entries)-1 {
+			t.cursor++
+		}
+
+	case "up", "k":
+		if t.cursor > 0 {
+			t.cursor--
+		}
+
+	case "r", "enter":
+		// Restore the entry and select it in the (reloaded) tree behind the view.
+		if len(t.entries) == 0 {
+			break
+		}
+		e := t.entries[t.cursor]
+		p, err := restoreTrash(e)
+		if err != nil {
+			m.status = "restore failed: " + err.Error()
+			break
+		}
+		t.remove(t.cursor)
+		if err := m.reload(p); err != nil {
+			m.status = "reload failed: " + err.Error()
+			break
+		}
+		m.status = "restored " + m.displayPath(p)
+		m.record("restore "+m.displayPath(p), fileOp{Kind: opRestore, To: p})
+
+	case "D":
+		// Purge permanently, after confirmation. Deliberately not x, which is
+		// too close to the X that opens and closes this view.
+		if len(t.entries) == 0 {
+			break
+		}
+		i := t.cursor
+		e := t.entries[i]
+		m.confirm("permanently delete "+m.displayPath(e.OrigPath)+"?", func(m *model) tea.Cmd {
+			if err := purgeTrash(e); err != nil {
+				m.status = "purge failed: " + err.Error()
+				return nil
+			}
+			m.trash.remove(i)
+			m.status = "purged " + m.displayPath(e.OrigPath)
+			if err := markPurged(e.Name); err != nil {
+				m.status += " (undo log: " + err.Error() + ")"
+			}
+			return nil
+		})
+	}
+	t.scroll = scrollWindow(t.cursor, t.scroll, len(t.entries), m.height)
+	return m, nil
+}
+
+// remove drops entry i from the listing and keeps the cursor in range.
+func (t *trashView) remove(i int) {
+	t.entries = append(t.entries[:i], t.entries[i+1:]...)
+	if t.cursor >= len(t.entries) && t.cursor > 0 {
+		t.cursor--
+	}
+}
+
+// view renders the trash listing: deletion time and original location.
+func (t *trashView) view(m model) string {
+	return m.frame("nnav - Trash", len(t.entries), func(i int) string {
+		e := t.entries[i]
+		return e.Deleted.Format("2006-01-02 15:04") + "  " + m.displayPath(e.OrigPath)
+	}, t.cursor, t.scroll)
+}
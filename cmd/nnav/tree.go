@@ -2,12 +2,14 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"errors"
 	"os"
 	"path/filepath"
 	"regexp"
 	"sort"
 	"strings"
+	"time"
 )
 
 // headingRE matches Markdown headings (# through ######) and captures the text.
@@ -20,6 +22,12 @@ var headingRE = regexp.MustCompile(`^\s*#{1,6}\s*(.+?)\s*$`)
 //   - IsDir: whether this is a directory.
 //   - Expanded: whether the directory is expanded in the TUI.
 //   - Title: optional, extracted title from the file’s first Markdown heading.
+//   - ModTime: last modification time (files only).
+//   - Matches: lines matching the active search (for directories, the total
+//     of the notes below).
+//   - Kind: kindEntry for files and directories on disk, kindSearch for a
+//     saved search shown as a virtual folder (IsDir is set, Path is not a
+//     real path, Query holds the search).
 //   - Children: nested files/directories if IsDir is true.
 type Node struct {
 	Name     string
@@ -27,49 +35,115 @@ type Node struct {
 	IsDir    bool
 	Expanded bool
 	Title    string
+	ModTime  time.Time
+	Matches  int
+	Kind     nodeKind
+	Query    string
 	Children []*Node
 }
 
+// nodeKind tells entries on disk from virtual ones (see Node.Kind).
+type nodeKind int
+
+const (
+	kindEntry  nodeKind = iota // file or directory on disk
+	kindSearch                 // saved search: its children are the matching notes
+)
+
 // scanTitle returns the first Markdown heading found in the file and whether
-// the file contains term (case-insensitive). If term is empty, it always
-// matches. The search is performed while scanning for the heading to avoid
-// double reads.
-func scanTitle(p, term string) (string, bool) {
-	if safe, ok := safePathWithinNotes(p); ok {
-		f, err := os.Open(safe)
-		if err != nil {
-			return "", false
-		}
-		defer f.Close()
+// the note satisfies q (see scanNote). If q is nil, it always matches.
+func scanTitle(p string, q *query) (string, bool) {
+	sc := scanNote(p, q, false)
+	return sc.Title, sc.Match
+}
 
-		s := bufio.NewScanner(f)
-		s.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+// noteScan is the outcome of scanNote.
+//   - Title: first Markdown heading ("" when there is none).
+//   - Match: whether the note satisfies the query.
+//   - Count: number of lines matching a content term of the query.
+//   - Lines: every line of the note (collect mode only).
+//   - Hits: 0-based numbers of the matching lines, in order (collect mode only).
+type noteScan struct {
+	Title string
+	Match bool
+	Count int
+	Lines []string
+	Hits  []int
+}
 
-		title := ""
-		found := term == ""
-		lower := strings.ToLower(term)
+// scanNote reads the note at p once, looking for its title and evaluating q
+// (see query.matches) line by line to avoid double reads. Matching lines are
+// counted for the tree's badges, so with content terms the whole file is
+// read; otherwise the scan stops at the title. A name search that needs
+// neither is decided from p without opening the note (the title is left
+// empty). With collect set, every line is kept and the matching ones
+// recorded (see the results view).
+func scanNote(p string, q *query, collect bool) noteScan {
+	var sc noteScan
+	if q.nameOnly() && !collect {
+		sc.Match = q.matches(q.newFacts(p))
+		return sc
+	}
+	safe, ok := safePathWithinNotes(p)
+	if !ok {
+		sc.Match = q == nil
+		return sc
+	}
+	f, err := os.Open(safe)
+	if err != nil {
+		return sc
+	}
+	defer f.Close()
 
-		for s.Scan() {
-			line := s.Text()
-			if m := headingRE.FindStringSubmatch(line); m != nil && title == "" {
-				title = m[1]
-			}
-			if !found && strings.Contains(strings.ToLower(line), lower) {
-				found = true
-			}
-			if found && title != "" {
-				break
+	s := bufio.NewScanner(f)
+	s.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+
+	var facts *noteFacts
+	if q != nil {
+		facts = q.newFacts(p)
+	}
+
+	for n := 0; s.Scan(); n++ {
+		line := s.Text()
+		if m := headingRE.FindStringSubmatch(line); m != nil && sc.Title == "" {
+			sc.Title = m[1]
+		}
+		if q != nil && q.matchLine(facts, line) {
+			sc.Count++
+			if collect {
+				sc.Hits = append(sc.Hits, n)
 			}
 		}
-		return title, found
+		if collect {
+			sc.Lines = append(sc.Lines, line)
+			continue
+		}
+		if sc.Title != "" && (q == nil || len(q.terms) == 0) {
+			break
+		}
+	}
+	if q == nil {
+		sc.Match = true
+		return sc
 	}
-	return "", term == ""
+	facts.title = sc.Title
+	if facts.title == "" {
+		facts.title = filepath.Base(p)
+	}
+	sc.Match = q.matches(facts)
+	return sc
 }
 
 // buildTree constructs a Node tree starting at the given root path.
 // Validates that root exists, is a directory, and is listable by the user.
 // Returns a Node with populated children for the top level.
-func buildTree(root, term string) (*Node, error) {
+func buildTree(root string, q *query) (*Node, error) {
+	return buildTreeCtx(context.Background(), root, q)
+}
+
+// buildTreeCtx is buildTree for a search that may be abandoned: the scan stops
+// with ctx.Err() as soon as ctx is cancelled (see the live search).
+func buildTreeCtx(ctx context.Context, root string, q *query) (*Node, error) {
 	info, err := os.Stat(root)
 	if err != nil {
 		return nil, err
@@ -84,10 +158,14 @@ func buildTree(root, term string) (*Node, error) {
 	// Root node is always marked Expanded so children are shown initially.
 	rootNode := &Node{Name: filepath.Base(root), Path: root, IsDir: true, Expanded: true}
 
-	children, err := readDirNodes(root, term)
+	children, err := readDirNodesCtx(ctx, root, q)
 	if err != nil {
 		return nil, err
 	}
+	// Saved searches head the unfiltered tree as virtual folders.
+	if q == nil {
+		children = append(savedSearchNodes(), children...)
+	}
 	rootNode.Children = children
 	return rootNode, nil
 }
@@ -100,12 +178,28 @@ func buildTree(root, term string) (*Node, error) {
 //
 // Filtering:
 //   - Skips entries that cannot be stat()’d.
-//   - Skips dirs that cannot be listed (permissions).
+//   - Skips dirs that cannot be listed (permissions), the .nnav-trash dir
+//     and the templates dir.
 //   - Skips files without allowed extensions (.md, .txt).
 //   - Skips unreadable files.
-//   - Extracts a title for note files via scanTitle().
-//   - When term is set, recursively keep only files containing the term.
-func readDirNodes(dir, term string) ([]*Node, error) {
+//   - Extracts a title for note files via scanTitle(), or from the index
+//     (see noteIndex) for notes that did not change since they were indexed.
+//   - When q is set, recursively keep only files matching the query and its
+//     date/size filter (checked on the FileInfo, before a note is read).
+func readDirNodes(dir string, q *query) ([]*Node, error) {
+	return readDirNodesCtx(context.Background(), dir, q)
+}
+
+// readDirNodesCtx is readDirNodes with cancellation, checked between entries.
+func readDirNodesCtx(ctx context.Context, dir string, q *query) ([]*Node, error) {
+	// The templates directory may live inside the notes tree; it is not a note
+	// folder. Resolved once, not for every directory a search descends into.
+	tmplDir, _ := templatesDir()
+	return readDirNodesIn(ctx, dir, q, tmplDir)
+}
+
+// readDirNodesIn is readDirNodesCtx with the templates dir already resolved.
+func readDirNodesIn(ctx context.Context, dir string, q *query, tmplDir string) ([]*Node, error) {
 	ents, err := os.ReadDir(dir)
 	if err != nil {
 		return nil, err
@@ -114,17 +208,16 @@ func readDirNodes(dir, term string) ([]*Node, error) {
 	// Sort: directories first, then alphabetical (case-insensitive).
 	sort.Slice(ents, func(i, j int) bool {
 		a, b := ents[i], ents[j]
-		if a.IsDir() && !b.IsDir() {
-			return true
-		}
-		if !a.IsDir() && b.IsDir() {
-			return false
-		}
-		return strings.ToLower(a.Name()) < strings.ToLower(b.Name())
+		return entryLess(a.IsDir(), a.Name(), b.IsDir(), b.Name())
 	})
 
+	ix := currentIndex()
+
 	nodes := make([]*Node, 0, len(ents))
 	for _, e := range ents {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		name := e.Name()
 		p := filepath.Join(dir, name)
 
@@ -134,17 +227,26 @@ func readDirNodes(dir, term string) ([]*Node, error) {
 		}
 
 		if info.IsDir() {
+			if hiddenDir(name, p, tmplDir) {
+				continue // nnav's own trash/templates are not note folders
+			}
 			if !isListableDir(p) {
 				continue // skip unreadable directories
 			}
 			var kids []*Node
-			if term != "" {
-				kids, err = readDirNodes(p, term)
+			if q != nil {
+				kids, err = readDirNodesIn(ctx, p, q, tmplDir)
+				if errors.Is(err, context.Canceled) {
+					return nil, err
+				}
 				if err != nil || len(kids) == 0 {
 					continue
 				}
 			}
-			n := &Node{Name: name, Path: p, IsDir: true, Children: kids, Expanded: term != ""}
+			n := &Node{Name: name, Path: p, IsDir: true, Children: kids, Expanded: q != nil}
+			for _, k := range kids {
+				n.Matches += k.Matches
+			}
 			nodes = append(nodes, n)
 			continue
 		}
@@ -154,17 +256,170 @@ func readDirNodes(dir, term string) ([]*Node, error) {
 		if !allowedExts[ext] {
 			continue
 		}
+		if q.nameOnly() && !q.matches(q.newFacts(p)) {
+			continue // ruled out by name, without opening the note
+		}
 		if !isReadableFile(p) {
 			continue // skip unreadable files
 		}
 
-		title, match := scanTitle(p, term)
-		if term != "" && !match {
+		// Notes unchanged since they were indexed are only read when the
+		// index cannot rule them out of the search.
+		if !q.keep(info) {
+			continue // outside the date/size filter
+		}
+		var sc noteScan
+		id, indexed := ix.lookup(p, info)
+		switch {
+		case indexed && (q == nil || q.expr == nil):
+			sc = noteScan{Title: ix.Docs[id].Title, Match: true}
+		case indexed && !q.mayMatch(ix, id):
+			sc = noteScan{Title: ix.Docs[id].Title}
+		default:
+			sc = scanNote(p, q, false)
+			if indexed && sc.Title == "" {
+				sc.Title = ix.Docs[id].Title // name searches do not read the note
+			}
+		}
+		if !sc.Match {
 			continue
 		}
-		n := &Node{Name: name, Path: p, Title: title}
+		n := &Node{Name: name, Path: p, Title: sc.Title, ModTime: info.ModTime(), Matches: sc.Count}
 		nodes = append(nodes, n)
 	}
 	return nodes, nil
 }
 
+// walkNotes calls fn for every note below dir, depth-first in tree order.
+// Directories are listed with readDirNodes, so exactly the notes shown in the
+// TUI are visited; directories for which skip returns true are left out.
+func walkNotes(dir string, skip func(p string) bool, fn func(n *Node) error) error {
+	nodes, err := readDirNodes(dir, nil)
+	if err != nil {
+		return err
+	}
+	for _, n := range nodes {
+		if n.IsDir {
+			if skip != nil && skip(n.Path) {
+				continue
+			}
+			if err := walkNotes(n.Path, skip, fn); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := fn(n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hiddenDir reports whether a directory holds nnav's own files rather than
+// notes: the .nnav-trash dir (browsed through the trash view) and the
+// templates dir (picked from when creating a note).
+func hiddenDir(name, p, tmplDir string) bool {
+	return name == notesTrashDir || (tmplDir != "" && filepath.Clean(p) == filepath.Clean(tmplDir))
+}
+
+// entryLess defines the tree ordering: directories before files, then
+// case-insensitive by name. Shared by readDirNodes and in-place insertions.
+func entryLess(aDir bool, aName string, bDir bool, bName string) bool {
+	if aDir != bDir {
+		return aDir
+	}
+	return strings.ToLower(aName) < strings.ToLower(bName)
+}
+
+// childNamed returns the direct child of n called name, or nil.
+func childNamed(n *Node, name string) *Node {
+	for _, c := range n.Children {
+		if c.Name == name && c.Kind == kindEntry {
+			return c
+		}
+	}
+	return nil
+}
+
+// dirState records, for every loaded directory below n, whether it is expanded.
+// Paths are used as keys so the state survives a full rebuild of the tree.
+func dirState(n *Node, out map[string]bool) {
+	for _, c := range n.Children {
+		if c.IsDir {
+			out[c.Path] = c.Expanded
+			dirState(c, out)
+		}
+	}
+}
+
+// restoreDirState re-applies a snapshot taken with dirState to a freshly built tree.
+// Directories not present in the snapshot keep whatever state buildTree gave them.
+func restoreDirState(n *Node, state map[string]bool, q *query) {
+	for _, c := range n.Children {
+		if !c.IsDir {
+			continue
+		}
+		expanded, known := state[c.Path]
+		if !known {
+			continue
+		}
+		if !expanded {
+			c.Expanded = false
+			continue
+		}
+		if err := expandIfNeeded(c, q); err == nil {
+			restoreDirState(c, state, q)
+		}
+	}
+}
+
+// parentOf returns the node whose Children contain target, or nil.
+func parentOf(n, target *Node) *Node {
+	for _, c := range n.Children {
+		if c == target {
+			return n
+		}
+		if c.IsDir {
+			if p := parentOf(c, target); p != nil {
+				return p
+			}
+		}
+	}
+	return nil
+}
+
+// insertChild adds c to parent.Children at its sorted position, after the
+// saved searches that lead the children of the root.
+func insertChild(parent, c *Node) {
+	skip := 0
+	for skip < len(parent.Children) && parent.Children[skip].Kind == kindSearch {
+		skip++
+	}
+	i := skip + sort.Search(len(parent.Children)-skip, func(i int) bool {
+		o := parent.Children[skip+i]
+		return entryLess(c.IsDir, c.Name, o.IsDir, o.Name)
+	})
+	parent.Children = append(parent.Children, nil)
+	copy(parent.Children[i+1:], parent.Children[i:])
+	parent.Children[i] = c
+}
+
+// removeChild drops c from parent.Children, if present.
+func removeChild(parent, c *Node) {
+	for i, o := range parent.Children {
+		if o == c {
+			parent.Children = append(parent.Children[:i], parent.Children[i+1:]...)
+			return
+		}
+	}
+}
+
+// setPath moves n to newPath in memory, rewriting Name and the paths of all
+// loaded descendants so the subtree stays consistent after a rename or move.
+func setPath(n *Node, newPath string) {
+	n.Path = newPath
+	n.Name = filepath.Base(newPath)
+	for _, c := range n.Children {
+		setPath(c, filepath.Join(newPath, c.Name))
+	}
+}
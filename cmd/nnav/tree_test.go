@@ -0,0 +1,42 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInsertChild(t *testing.T) {
+	names := func(n *Node) string {
+		var out []string
+		for _, c := range n.Children {
+			out = append(out, c.Name)
+		}
+		return strings.Join(out, " ")
+	}
+	root := &Node{IsDir: true, Children: []*Node{
+		{Name: "zz todo", IsDir: true, Kind: kindSearch},
+		{Name: "aa drafts", IsDir: true, Kind: kindSearch},
+		{Name: "m", IsDir: true},
+		{Name: "b.md"},
+		{Name: "y.md"},
+	}}
+	for _, c := range []*Node{
+		{Name: "a", IsDir: true},
+		{Name: "zz", IsDir: true},
+		{Name: "a.md"},
+		{Name: "c.md"},
+		{Name: "z.md"},
+	} {
+		insertChild(root, c)
+	}
+	if got, want := names(root), "zz todo aa drafts a m zz a.md b.md c.md y.md z.md"; got != want {
+		t.Errorf("children = %q, want %q", got, want)
+	}
+
+	dir := &Node{IsDir: true}
+	insertChild(dir, &Node{Name: "b.md"})
+	insertChild(dir, &Node{Name: "a.md"})
+	if got := names(dir); got != "a.md b.md" {
+		t.Errorf("children = %q", got)
+	}
+}
@@ -0,0 +1,390 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// defaultInboxDir is the directory processed by the triage mode, relative to
+// the notes root, when "inboxdir" is not set in ~/.nnav.
+const defaultInboxDir = "inbox"
+
+// triageHelp is the footer shown while triaging the inbox.
+const triageHelp = "<f> file • <d> trash • <t> tag • <s> skip • <p> previous • <e> edit • ↑/↓ scroll • <esc> done"
+
+// triage steps through the notes of the inbox directory one at a time.
+//   - dir: the inbox directory.
+//   - notes: inbox notes still to be processed (filed or trashed ones are dropped).
+//   - index: the note currently shown.
+//   - preview: contents of the current note, one entry per line.
+//   - filed/trashed/tagged: counters for the summary shown when done.
+type triage struct {
+	dir     string
+	notes   []*Node
+	index   int
+	preview []string
+	scroll  int
+	filed   int
+	trashed int
+	tagged  int
+}
+
+// inboxDir returns the inbox directory inside the notes root ("inboxdir" in
+// ~/.nnav, relative to notesdir), validated with safeJoinWithin.
+func inboxDir() (string, error) {
+	root, err := notesRoot()
+	if err != nil {
+		return "", err
+	}
+	cfg, _ := loadConfig()
+	rel := strings.TrimSpace(cfg["inboxdir"])
+	if rel == "" {
+		rel = defaultInboxDir
+	}
+	if _, err := safeJoinWithin(root, rel); err != nil {
+		return "", fmt.Errorf("invalid inboxdir: %w", err)
+	}
+	return filepath.Join(root, filepath.Clean(rel)), nil
+}
+
+// openTriage collects the inbox notes (through walkNotes, so the same notes
+// as in the tree) and switches to the triage view.
+func (m *model) openTriage() {
+	dir, err := inboxDir()
+	if err != nil {
+		m.status = "triage: " + err.Error()
+		return
+	}
+	if !isListableDir(dir) {
+		m.status = "triage: no inbox directory " + m.displayPath(dir) + " (set inboxdir in ~/.nnav)"
+		return
+	}
+	t := &triage{dir: dir}
+	if err := walkNotes(dir, nil, func(n *Node) error {
+		t.notes = append(t.notes, n)
+		return nil
+	}); err != nil {
+		m.status = "triage: " + err.Error()
+		return
+	}
+	if len(t.notes) == 0 {
+		m.status = "inbox is empty"
+		return
+	}
+	m.triage = t
+	t.load()
+	m.status = triageHelp
+}
+
+// current returns the note being triaged.
+func (t *triage) current() *Node {
+	return t.notes[t.index]
+}
+
+// load reads the current note into the preview.
+func (t *triage) load() {
+	t.scroll = 0
+	t.preview = nil
+	safe, ok := safePathWithinNotes(t.current().Path)
+	if !ok {
+		t.preview = []string{"(outside notes dir)"}
+		return
+	}
+	// #nosec G304 -- safe is validated against the notes root.
+	f, err := os.Open(safe)
+	if err != nil {
+		t.preview = []string{"(" + err.Error() + ")"}
+		return
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	s.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for s.Scan() {
+		t.preview = append(t.preview, strings.ReplaceAll(s.Text(), "\t", "    "))
+	}
+}
+
+// drop removes the current note from the queue (after filing or trashing it).
+func (t *triage) drop() {
+	t.notes = append(t.notes[:t.index], t.notes[t.index+1:]...)
+}
+
+// triageNext shows the note at the current index, or ends the triage once every
+// note has been handled.
+func (m *model) triageNext() {
+	t := m.triage
+	if t.index >= len(t.notes) {
+		m.closeTriage()
+		return
+	}
+	t.load()
+}
+
+// closeTriage leaves the triage view, reloads the tree and summarizes.
+func (m *model) closeTriage() {
+	t := m.triage
+	m.triage = nil
+	skipped := len(t.notes)
+	m.status = fmt.Sprintf("triage done: %d filed, %d trashed, %d tagged, %d left in inbox", t.filed, t.trashed, t.tagged, skipped)
+	_ = m.reload(t.dir)
+}
+
+// updateTriage handles keys while triaging.
+func (m model) updateTriage(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	t := m.triage
+	n := t.current()
+	page := max(1, m.height-4)
+	switch msg.String() {
+	case "esc", "q", "ctrl+c":
+		m.closeTriage()
+
+	case "s", " ":
+		// Skip: leave the note in the inbox.
+		t.index++
+		m.triageNext()
+
+	case "p":
+		if t.index > 0 {
+			t.index--
+			t.load()
+		}
+
+	case "f":
+		// File: fuzzy-pick a destination directory and move the note there.
+		dirs, err := noteDirs()
+		if err != nil {
+			m.status = "file failed: " + err.Error()
+			break
+		}
+		var labels []string
+		var targets []string
+		for _, d := range dirs {
+			if d == filepath.Dir(n.Path) {
+				continue
+			}
+			labels = append(labels, m.displayPath(d))
+			targets = append(targets, d)
+		}
+		m.openFinder("file "+n.Name+" into", labels, func(m *model, i int) tea.Cmd {
+			p, err := moveEntry(n.Path, targets[i])
+			if err != nil {
+				m.status = "file failed: " + err.Error()
+				return nil
+			}
+			m.status = "filed " + n.Name + " → " + m.displayPath(targets[i])
+			m.record("move "+m.displayPath(n.Path)+" → "+m.displayPath(targets[i]), fileOp{Kind: opMove, From: n.Path, To: p})
+			m.triage.filed++
+			m.triage.drop()
+			m.triageNext()
+			return nil
+		})
+
+	case "d", "x":
+		// Trash the note.
+		e, err := trashNote(n.Path)
+		if err != nil {
+			m.status = "trash failed: " + err.Error()
+			break
+		}
+		m.status = "trashed " + n.Name
+		m.record("trash "+m.displayPath(n.Path), fileOp{Kind: opTrash, From: n.Path, Trash: e.Name})
+		t.trashed++
+		t.drop()
+		m.triageNext()
+
+	case "t":
+		// Tag: add tags to the note's frontmatter (logged as an edit).
+		m.ask("tags for "+n.Name+": ", "", func(m *model, input string) tea.Cmd {
+			tags := parseTags(input)
+			if len(tags) == 0 {
+				m.status = "no tags given"
+				return nil
+			}
+			if m.tagNote(n.Path, tags) && m.triage != nil {
+				m.triage.tagged++
+				m.triage.load()
+			}
+			return nil
+		})
+
+	case "e", "enter":
+		// Edit in the external editor; the preview is refreshed on return.
+		return m, m.openInEditor(n.Path)
+
+	case "down", "j":
+		t.scroll++
+	case "up", "k":
+		t.scroll--
+	case "pgdown":
+		t.scroll += page
+	case "pgup":
+		t.scroll -= page
+	}
+	if m.triage != nil {
+		t.scroll = max(0, min(t.scroll, len(t.preview)-page))
+	}
+	return m, nil
+}
+
+// view renders the current inbox note with its position in the queue; matches
+// of an active search are highlighted.
+func (t *triage) view(m model) string {
+	title := fmt.Sprintf("inbox %d/%d: %s", t.index+1, len(t.notes), m.displayPath(t.current().Path))
+	return m.frame(title, len(t.preview), func(i int) string {
+		return m.query.highlight(t.preview[i], false)
+	}, -1, t.scroll)
+}
+
+// tagNote adds tags to the frontmatter of the note at p and logs the edit.
+// Reports whether the note was changed; the outcome is left in the status.
+func (m *model) tagNote(p string, tags []string) bool {
+	safe, ok := safePathWithinNotes(p)
+	if !ok {
+		m.status = "tag failed: outside notes dir"
+		return false
+	}
+	// #nosec G304 -- safe is validated against the notes root.
+	data, err := os.ReadFile(safe)
+	if err != nil {
+		m.status = "tag failed: " + err.Error()
+		return false
+	}
+	before := string(data)
+	after := addTags(before, tags)
+	if after == before {
+		m.status = filepath.Base(p) + " already has these tags"
+		return false
+	}
+	if err := rewriteNote(p, before, after); err != nil {
+		m.status = "tag failed: " + err.Error()
+		return false
+	}
+	m.status = "tagged " + filepath.Base(p) + ": " + strings.Join(tags, ", ")
+	m.record("tag "+m.displayPath(p), fileOp{Kind: opEdit, To: p, Before: before, After: after})
+	return true
+}
+
+// parseTags splits user input such as "#work, ideas later" into tags.
+func parseTags(s string) []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, f := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' }) {
+		tag := strings.TrimLeft(f, "#")
+		if tag != "" && !seen[tag] {
+			seen[tag] = true
+			out = append(out, tag)
+		}
+	}
+	return out
+}
+
+// addTags returns body with tags added to the "tags" key of its YAML
+// frontmatter, creating the frontmatter or the key when missing. Tags that
+// are already listed are not repeated. Inline lists (tags: [a, b]),
+// comma-separated values (tags: a, b) and block lists ("  - a" lines) are
+// understood; inline forms are rewritten as [a, b, c].
+func addTags(body string, tags []string) string {
+	lines := strings.Split(body, "\n")
+	end := -1
+	if strings.TrimRight(lines[0], "\r") == "---" {
+		for i := 1; i < len(lines); i++ {
+			if l := strings.TrimRight(lines[i], "\r"); l == "---" || l == "..." {
+				end = i
+				break
+			}
+		}
+	}
+	if end < 0 {
+		return "---\ntags: [" + strings.Join(tags, ", ") + "]\n---\n" + body
+	}
+
+	missing := func(have []string) []string {
+		var out []string
+		for _, t := range tags {
+			found := false
+			for _, h := range have {
+				if h == t {
+					found = true
+					break
+				}
+			}
+			if !found {
+				out = append(out, t)
+			}
+		}
+		return out
+	}
+
+	for i := 1; i < end; i++ {
+		key, val, ok := strings.Cut(strings.TrimRight(lines[i], "\r"), ":")
+		if !ok || key != "tags" {
+			continue
+		}
+		val = strings.TrimSpace(val)
+		if val != "" {
+			var have []string
+			for _, h := range strings.Split(strings.Trim(val, "[]"), ",") {
+				if h = strings.Trim(strings.TrimSpace(h), `"'`); h != "" {
+					have = append(have, h)
+				}
+			}
+			lines[i] = "tags: [" + strings.Join(append(have, missing(have)...), ", ") + "]"
+			return strings.Join(lines, "\n")
+		}
+		// Block list: collect the "- tag" lines below the key.
+		j, indent := i+1, "  "
+		var have []string
+		for ; j < end; j++ {
+			item := strings.TrimSpace(lines[j])
+			if !strings.HasPrefix(item, "-") {
+				break
+			}
+			if j == i+1 {
+				indent = lines[j][:len(lines[j])-len(strings.TrimLeft(lines[j], " \t"))]
+			}
+			have = append(have, strings.Trim(strings.TrimSpace(strings.TrimPrefix(item, "-")), `"'`))
+		}
+		var add []string
+		for _, t := range missing(have) {
+			add = append(add, indent+"- "+t)
+		}
+		lines = append(lines[:j], append(add, lines[j:]...)...)
+		return strings.Join(lines, "\n")
+	}
+	lines = append(lines[:end], append([]string{"tags: [" + strings.Join(tags, ", ") + "]"}, lines[end:]...)...)
+	return strings.Join(lines, "\n")
+}
+
+// noteDirs lists the notes root and every directory below it that the tree
+// would show (same filtering as readDirNodes), without reading any notes.
+func noteDirs() ([]string, error) {
+	root, err := notesRoot()
+	if err != nil {
+		return nil, err
+	}
+	tmplDir, _ := templatesDir()
+	var dirs []string
+	err = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if d != nil && d.IsDir() && p != root {
+				return fs.SkipDir
+			}
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if p != root && (hiddenDir(d.Name(), p, tmplDir) || !isListableDir(p)) {
+			return fs.SkipDir
+		}
+		dirs = append(dirs, p)
+		return nil
+	})
+	return dirs, err
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -13,7 +15,10 @@ import (
 
 // Short, discoverable key map displayed in the status/footer.
 // Keep this in sync with Update() to avoid confusing users.
-const helpText = "↑/↓ move • → expand • ← collapse • <enter> open • <q> quit"
+const helpText = "↑/↓ move • → expand • ← collapse • <enter> open • <n> new • <N> mkdir • <R> rename • <M> move • <space> select • <S> split • <J> merge • <d> delete • <X> trash • <u> undo • </> search • <F> results • <f> filter • <alt+r> regex • <alt+c> case • <alt+w> word • <alt+s> scope • <ctrl+p> find • <t> today • <i> IDs • <A> archive • <I> inbox • <q> quit"
+
+// periodKeys maps the periodic-note keys to their kind.
+var periodKeys = map[string]string{"t": "daily", "w": "weekly", "m": "monthly"}
 
 // Soft viewport margins so the cursor isn't pinned to the edges while scrolling.
 const minTopMargin = 2    // lines to keep above cursor
@@ -33,25 +38,58 @@ type Visible struct {
 // - scroll: top index of the viewport window within visible.
 // - status: footer text for help/errors.
 // - width/height: last-known terminal dimensions used for layout.
+// - prompt: inline input that temporarily replaces the footer (nil when closed).
+// - picker: modal directory chooser used by move (nil when closed).
+// - trash: trash listing with restore/purge (nil when closed).
+// - chooser: modal single-choice list, e.g. templates (nil when closed).
+// - pager: scrollable preview of a pending change (nil when closed).
+// - finder: fuzzy-filtered list, e.g. the destination when filing (nil when closed).
+// - triage: inbox triage mode, one note at a time (nil when closed).
+// - results: matching lines of the active search with context (nil when closed).
+// - searchSeq/searchCancel/preSearch: live search bookkeeping (see search.go).
+// - marked: notes selected with <space> for multi-note actions such as merge.
+// - archive/showArchive: the archive dir and whether its subtree is listed.
+// - indexing/indexAgain: background index refresh state (see startIndexRefresh).
 type model struct {
-	root       *Node
-	cursor     int
-	visible    []Visible
-	status     string
-	width      int
-	height     int
-	scroll     int // top index of visible window
-	searchTerm string
+	root         *Node
+	cursor       int
+	visible      []Visible
+	status       string
+	width        int
+	height       int
+	scroll       int        // top index of visible window
+	query        *query     // active search filter (nil: none)
+	searchOpts   searchOpts // search switches such as regex or whole-word mode
+	prompt       *prompt
+	picker       *dirPicker
+	trash        *trashView
+	chooser      *chooser
+	pager        *pager
+	finder       *finder
+	triage       *triage
+	results      *results
+	marked       map[string]bool    // multi-selected note paths
+	showIDs      bool               // reveal Zettelkasten ID prefixes in the tree
+	archive      string             // archive dir (see archiveDir)
+	showArchive  bool               // list the archive subtree (toggled with <A>)
+	searchSeq    int                // id of the latest live search
+	searchCancel context.CancelFunc // cancels the live search in flight
+	preSearch    *searchState       // tree state to restore when the search is cleared
+	indexing     bool               // background index refresh in flight
+	indexAgain   bool               // refresh again when the current one is done
 }
 
 // message sent after we return from the editor
 // Used to trigger a post-editor refresh without coupling to exec exit codes.
-type resumedMsg struct{}
+// path is the note that was edited so the cursor can return to it.
+type resumedMsg struct{ path string }
 
 // newModel initializes the model and precomputes the initial visible list.
 // Starts with the root expanded at top-level.
-func newModel(root *Node, term string) model {
-	m := model{root: root, cursor: 0, status: helpText, searchTerm: term}
+func newModel(root *Node, q *query, opts searchOpts) model {
+	archive, _ := archiveDir()
+	// Init starts the first index refresh.
+	m := model{root: root, cursor: 0, status: helpText, query: q, searchOpts: opts, marked: map[string]bool{}, archive: archive, indexing: true}
 	m.recompute()
 	return m
 }
@@ -60,14 +98,22 @@ func newModel(root *Node, term string) model {
 // - files: Title if present, else filename
 // - dirs: directory name
 // Prefer a human-friendly title to make scanning large lists easier.
-func displayName(n *Node) string {
+// Zettelkasten ID prefixes ("202610161432-") are hidden unless showIDs is set.
+func displayName(n *Node, showIDs bool) string {
 	if n.IsDir {
 		return n.Name
 	}
+	id, rest := splitNoteID(strings.TrimSuffix(n.Name, filepath.Ext(n.Name)))
+	name := n.Name
 	if t := strings.TrimSpace(n.Title); t != "" {
-		return t
+		name = t
+	} else if id != "" && rest != "" {
+		name = rest
+	}
+	if showIDs && id != "" {
+		return id + " " + name
 	}
-	return n.Name
+	return name
 }
 
 // recompute rebuilds the flattened visible list from the current tree state.
@@ -81,6 +127,17 @@ func (m *model) recompute() {
 		flatten(c, 0, &m.visible)
 	}
 
+	// Drop the archive subtree unless the user asked to see it.
+	if !m.showArchive && m.archive != "" {
+		kept := m.visible[:0]
+		for _, v := range m.visible {
+			if !inSubtree(v.N.Path, m.archive) {
+				kept = append(kept, v)
+			}
+		}
+		m.visible = kept
+	}
+
 	// Clamp cursor within new bounds.
 	if m.cursor >= len(m.visible) {
 		if len(m.visible) == 0 {
@@ -96,25 +153,32 @@ func (m *model) recompute() {
 // adjustScroll ensures the viewport scroll offset includes the cursor with margins.
 // Reserves space for a 2-line title and 2-line footer/status (total header/footer = 4).
 func (m *model) adjustScroll() {
-	if m.height <= 0 {
-		return
+	m.scroll = scrollWindow(m.cursor, m.scroll, len(m.visible), m.height)
+}
+
+// scrollWindow returns the scroll offset that keeps cursor inside a list of
+// total rows shown on a screen of the given height. Shared by every list view.
+func scrollWindow(cursor, scroll, total, height int) int {
+	if height <= 0 {
+		return scroll
 	}
 	// minus header/footer space: title (2 lines) + status (2 lines)
-	usable := m.height - 4
+	usable := height - 4
 	if usable <= 0 {
-		return
+		return scroll
 	}
 	// Ensure cursor stays within scroll window with margins
-	if m.cursor < m.scroll+minTopMargin {
-		m.scroll = max(0, m.cursor-minTopMargin)
+	if cursor < scroll+minTopMargin {
+		scroll = max(0, cursor-minTopMargin)
 	}
-	if m.cursor >= m.scroll+usable-minBottomMargin {
-		m.scroll = max(0, m.cursor-usable+minBottomMargin+1)
+	if cursor >= scroll+usable-minBottomMargin {
+		scroll = max(0, cursor-usable+minBottomMargin+1)
 	}
 	// Clamp scroll so we don't go past end
-	if m.scroll > max(0, len(m.visible)-usable) {
-		m.scroll = max(0, len(m.visible)-usable)
+	if scroll > max(0, total-usable) {
+		scroll = max(0, total-usable)
 	}
+	return scroll
 }
 
 // flatten appends n and (recursively) its expanded children to out,
@@ -144,7 +208,7 @@ func min(a, b int) int {
 }
 
 // Init implements Bubble Tea’s initializer—no async startup work needed.
-func (m model) Init() tea.Cmd { return nil }
+func (m model) Init() tea.Cmd { return refreshIndexCmd(m.root.Path) }
 
 // Update is the event loop: handles key presses, window resizes, and editor resume.
 // All state mutations funnel through here for predictable TUI behavior.
@@ -152,12 +216,82 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 
 	case tea.KeyMsg:
+		// An open prompt captures all keys until it is submitted or cancelled.
+		if m.prompt != nil {
+			return m.updatePrompt(msg)
+		}
+		if m.picker != nil {
+			return m.updatePicker(msg)
+		}
+		if m.finder != nil {
+			return m.updateFinder(msg)
+		}
+		if m.trash != nil {
+			return m.updateTrash(msg)
+		}
+		if m.chooser != nil {
+			return m.updateChooser(msg)
+		}
+		if m.pager != nil {
+			return m.updatePager(msg)
+		}
+		if m.triage != nil {
+			return m.updateTriage(msg)
+		}
+		if m.results != nil {
+			return m.updateResults(msg)
+		}
+
 		switch msg.String() {
 
-		case "q", "esc", "ctrl+c":
+		case "esc":
+			// Clear an active search first, then the filter; otherwise quit like q.
+			if m.query != nil {
+				if m.query.text == "" {
+					m.searchOpts.filter = noteFilter{}
+				}
+				m.clearSearch()
+				m.status = helpText
+				break
+			}
+			return m, tea.Quit
+
+		case "q", "ctrl+c":
 			// Exit cleanly from the alt screen back to the user's terminal.
 			return m, tea.Quit
 
+		case "/":
+			// Live search: filter the tree while typing.
+			m.openSearch()
+
+		case "alt+r":
+			// Toggle regex search mode (re-runs an active search).
+			return m, m.toggleSearchOpt("regex", &m.searchOpts.regex)
+
+		case "alt+c":
+			// Toggle case-sensitive search (smart-case when off).
+			return m, m.toggleSearchOpt("case-sensitive", &m.searchOpts.caseSensitive)
+
+		case "alt+w":
+			// Toggle whole-word search.
+			return m, m.toggleSearchOpt("whole-word", &m.searchOpts.word)
+
+		case "alt+s":
+			// Cycle what unqualified search terms match: content, title, name.
+			return m, m.cycleSearchScope()
+
+		case "f":
+			// Filter the tree by modification date and size.
+			m.openFilter()
+
+		case "F":
+			// Every matching line of the active search, with context.
+			return m, m.openResults()
+
+		case "ctrl+p":
+			// Fuzzy finder over every note (title and path).
+			return m, m.openNoteFinder()
+
 		case "down", "j":
 			// Cursor moves down within the visible list.
 			if m.cursor < len(m.visible)-1 {
@@ -179,7 +313,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			cur := m.visible[m.cursor].N
 			if cur.IsDir && !cur.Expanded {
-				if err := expandIfNeeded(cur, m.searchTerm); err != nil {
+				if err := expandIfNeeded(cur, m.query); err != nil {
 					m.status = "error: " + err.Error()
 				} else {
 					m.recompute()
@@ -204,42 +338,96 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			cur := m.visible[m.cursor].N
 			if !cur.IsDir {
-				// Resolve validated editor
-				edPath, edArgs, err := resolveEditor()
-				if err != nil {
-					m.status = "editor error: " + err.Error()
-					break
-				}
+				return m, m.openInEditor(cur.Path)
+			}
 
-				// Validate the file path remains inside notes root (defense-in-depth).
-				rootPath, err := notesRoot()
-				if err != nil {
-					m.status = "resolve notes root failed: " + err.Error()
-					break
-				}
-				rel, err := filepath.Rel(rootPath, cur.Path)
-				if err != nil {
-					m.status = "path error: " + err.Error()
-					break
-				}
-				safePath, err := safeJoinWithin(rootPath, rel)
-				if err != nil {
-					m.status = "unsafe path: " + err.Error()
-					break
-				}
+		case "n":
+			// Create a new note next to the selection, then open it in the editor.
+			m.newNote()
+
+		case "N":
+			// Create a subdirectory next to the selection.
+			m.newDir()
+
+		case "R":
+			// Rename the selected note or directory, or all notes selected
+			// with <space> using a pattern.
+			if len(m.markedPaths()) > 0 {
+				m.bulkRenameMarked()
+			} else {
+				m.renameSelected()
+			}
+
+		case "M":
+			// Move the selected note or directory to a directory chosen in a picker.
+			m.moveSelected()
+
+		case "d":
+			// Trash the selected note, or remove the selected directory if empty.
+			m.deleteSelected()
+
+		case "X":
+			// Browse the trash to restore or purge deleted notes.
+			m.openTrash()
+
+		case "t", "w", "m":
+			// Open (creating if needed) today's daily, weekly or monthly note.
+			return m, m.openPeriodic(periodKeys[msg.String()])
 
-				// Hand terminal control to the editor with TTY attached.
-				// tea.ExecProcess returns control to Bubble Tea and sends resumedMsg when done.
-				cmd := exec.Command(edPath, append(edArgs, safePath)...)
-				cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
-				return m, tea.ExecProcess(cmd, func(error) tea.Msg { return resumedMsg{} })
+		case "[", "]":
+			// Jump to the previous/next existing periodic note.
+			if msg.String() == "[" {
+				m.jumpPeriodic(-1)
+			} else {
+				m.jumpPeriodic(1)
 			}
 
+		case " ":
+			// Toggle the selected note in the multi-selection.
+			m.toggleMark()
+
+		case "S":
+			// Split the selected note into one note per heading (with preview).
+			m.splitSelected()
+
+		case "J":
+			// Merge the multi-selected notes into a new note (with preview).
+			m.mergeMarked()
+
+		case "i":
+			// Toggle display of Zettelkasten IDs next to note titles.
+			m.showIDs = !m.showIDs
+			m.status = "IDs hidden"
+			if m.showIDs {
+				m.status = "IDs shown"
+			}
+
+		case "I":
+			// Triage the inbox directory one note at a time.
+			m.openTriage()
+
+		case "A":
+			// Show or hide the archive subtree (see `nnav archive`).
+			m.showArchive = !m.showArchive
+			m.recompute()
+			m.status = "archive hidden"
+			if m.showArchive {
+				m.status = "archive shown"
+			}
+
+		case "u":
+			// Undo the last file operation (persisted across restarts).
+			m.undo(false)
+
+		case "ctrl+r":
+			// Redo the last undone file operation.
+			m.undo(true)
+
 		case "r":
 			// Manual refresh: rebuild the tree from disk and reset view state.
 			// Useful when files are added/removed externally.
 			rootPath, _ := notesRoot()
-			if root, err := buildTree(rootPath, m.searchTerm); err == nil {
+			if root, err := buildTree(rootPath, m.query); err == nil {
 				m.root = root
 				m.cursor = 0
 				m.recompute()
@@ -247,23 +435,52 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			} else {
 				m.status = "reload failed: " + err.Error()
 			}
+			return m, m.startIndexRefresh()
+		}
+
+	case notesListedMsg:
+		// The note finder's list has been collected.
+		m.fillNoteFinder(msg)
+
+	case searchDoneMsg:
+		// A background search finished (results of superseded searches are dropped).
+		m.applySearch(msg)
+
+	case resultsMsg:
+		// The matching lines for the results view have been collected.
+		m.fillResults(msg)
+
+	case indexDoneMsg:
+		// The background index refresh finished; later scans use the new index.
+		m.indexing = false
+		if msg.err != nil {
+			m.status = "index: " + msg.err.Error()
+		}
+		if m.indexAgain {
+			m.indexAgain = false
+			return m, m.startIndexRefresh()
 		}
 
 	case resumedMsg:
 		// After returning from the editor, rebuild tree and reset the help footer.
-		// This ensures titles/ordering reflect any edits or renames.
-		if rootPath, err := notesRoot(); err == nil {
-			if root, err := buildTree(rootPath, m.searchTerm); err == nil {
-				m.root = root
-				m.cursor = 0
-				m.recompute()
-				m.status = helpText
-			} else {
-				m.status = "reload failed: " + err.Error()
-			}
+		// This ensures titles/ordering reflect any edits or renames, while the
+		// expanded directories and the cursor stay on the note just edited.
+		if err := m.reload(msg.path); err == nil {
+			m.status = helpText
 		} else {
-			m.status = "resolve notes root failed: " + err.Error()
+			m.status = "reload failed: " + err.Error()
+		}
+		if m.triage != nil {
+			// Back from editing an inbox note: show its new contents.
+			m.triage.load()
+			m.status = triageHelp
 		}
+		if m.results != nil {
+			// Back from editing a match: refresh the lines shown.
+			m.status = resultsHelp
+			return m, tea.Batch(m.loadResults(), m.startIndexRefresh())
+		}
+		return m, m.startIndexRefresh()
 
 	case tea.WindowSizeMsg:
 		// Track terminal size for layout and scrolling calculations.
@@ -277,43 +494,105 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 // View renders the current screen using lipgloss styles.
 // Layout: title (2 lines), list (scrollable window), status/footer (2 lines).
 func (m model) View() string {
+	if m.picker != nil {
+		return m.picker.view(m)
+	}
+	if m.finder != nil {
+		return m.finder.view(m)
+	}
+	if m.trash != nil {
+		return m.trash.view(m)
+	}
+	if m.chooser != nil {
+		return m.chooser.view(m)
+	}
+	if m.pager != nil {
+		return m.pager.view(m)
+	}
+	if m.triage != nil {
+		return m.triage.view(m)
+	}
+	if m.results != nil {
+		return m.results.view(m)
+	}
+	title := "nnav - Notes Navigator"
+	if f := m.searchOpts.filter; f.active() {
+		title += " [" + f.spec + "]"
+	}
+	return m.frame(title, len(m.visible), func(i int) string {
+		line := renderLine(m.visible[i], m.showIDs, m.query, i == m.cursor)
+		if m.marked[m.visible[i].N.Path] {
+			line += " ✓" // multi-selected
+		}
+		return line
+	}, m.cursor, m.scroll)
+}
+
+// frame draws the shared screen layout for a list of n rows: a bold title,
+// the rows within the scroll window (line renders row i) with the cursor row
+// in reverse video, and the footer with the status line or open prompt.
+func (m model) frame(title string, n int, line func(i int) string, cursor, scroll int) string {
 	var b strings.Builder
 	titleStyle := lipgloss.NewStyle().Bold(true)
 	muted := lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
 	cursorStyle := lipgloss.NewStyle().Reverse(true)
 
-	b.WriteString(titleStyle.Render("nnav - Notes Navigator"))
+	b.WriteString(titleStyle.Render(title))
 	b.WriteString("\n\n")
 
-	// Render only the visible window
-	usable := m.height - 4
+	// Render only the visible window (multi-line titles take extra rows).
+	usable := m.height - 4 - strings.Count(title, "\n")
 	if usable < 1 {
-		usable = len(m.visible)
+		usable = n
 	}
-	end := min(len(m.visible), m.scroll+usable)
+	end := min(n, scroll+usable)
 
-	for i := m.scroll; i < end; i++ {
-		line := renderLine(m.visible[i])
-		if i == m.cursor {
+	for i := scroll; i < end; i++ {
+		l := line(i)
+		if i == cursor {
 			// Visual cursor: reverse video for strong affordance.
-			line = cursorStyle.Render(line)
+			l = cursorStyle.Render(l)
 		}
-		b.WriteString(line)
+		b.WriteString(l)
 		b.WriteString("\n")
 	}
 
-	// Footer/status line with help or error messages.
+	// Footer/status line with help or error messages, or the open prompt.
 	b.WriteString("\n")
-	b.WriteString(muted.Render(m.status))
+	if m.prompt != nil {
+		b.WriteString(m.prompt.view())
+	} else {
+		// Truncate rather than wrap so the footer never pushes the list off screen.
+		if m.width > 0 {
+			muted = muted.MaxWidth(m.width)
+		}
+		b.WriteString(muted.Render(m.status))
+	}
 	b.WriteString("\n")
 	return b.String()
 }
 
 // renderLine draws a single entry with indentation and a prefix glyph:
 // - ▸/▾ for directories (collapsed/expanded), • for files.
-func renderLine(v Visible) string {
+// While a search is active (q set), matched parts of the name are highlighted
+// and a badge shows the number of matching lines in the note or directory;
+// cursor tells whether the row is drawn in reverse video (see highlightRunes).
+func renderLine(v Visible, showIDs bool, q *query, cursor bool) string {
 	indent := strings.Repeat("  ", v.Depth)
 	prefix := "  "
+	if v.N.Kind == kindSearch {
+		// Saved search: a virtual folder with the number of notes found.
+		prefix = "▸ ⌕ "
+		if v.N.Expanded {
+			prefix = "▾ ⌕ "
+		}
+		name := lipgloss.NewStyle().Italic(true).Reverse(cursor).Render(v.N.Name)
+		if v.N.Expanded {
+			badge := lipgloss.NewStyle().Foreground(lipgloss.Color("8")).Reverse(cursor)
+			name += badge.Render(fmt.Sprintf(" (%d)", len(v.N.Children)))
+		}
+		return indent + prefix + name
+	}
 	if v.N.IsDir {
 		if v.N.Expanded {
 			prefix = "▾ "
@@ -323,21 +602,31 @@ func renderLine(v Visible) string {
 	} else {
 		prefix = "• "
 	}
-	name := displayName(v.N)
+	name := q.highlight(displayName(v.N, showIDs), cursor)
+	if q != nil && v.N.Matches > 0 {
+		badge := lipgloss.NewStyle().Foreground(lipgloss.Color("8")).Reverse(cursor)
+		name += badge.Render(fmt.Sprintf(" (%d)", v.N.Matches))
+	}
 	return indent + prefix + name
 }
 
 // expandIfNeeded lazily loads children for a directory if not already populated,
 // and marks it expanded. No-op for files or already-expanded dirs.
-func expandIfNeeded(n *Node, term string) error {
+func expandIfNeeded(n *Node, q *query) error {
 	if !n.IsDir {
 		return nil
 	}
+	if n.Kind == kindSearch {
+		if n.Expanded {
+			return nil
+		}
+		return expandSavedSearch(n)
+	}
 	if n.Expanded && len(n.Children) > 0 {
 		return nil
 	}
 	if len(n.Children) == 0 {
-		kids, err := readDirNodes(n.Path, term)
+		kids, err := readDirNodes(n.Path, q)
 		if err != nil {
 			return err
 		}
@@ -347,3 +636,152 @@ func expandIfNeeded(n *Node, term string) error {
 	return nil
 }
 
+// openInEditor validates p and returns a command that hands the terminal to
+// the configured editor. On failure it sets the status line and returns nil.
+func (m *model) openInEditor(p string) tea.Cmd {
+	return m.openInEditorAt(p, 0)
+}
+
+// openInEditorAt is openInEditor with the cursor placed on line (1-based;
+// 0 opens the note at the top), see editorFileArgs.
+func (m *model) openInEditorAt(p string, line int) tea.Cmd {
+	// Resolve validated editor
+	edPath, edArgs, err := resolveEditor()
+	if err != nil {
+		m.status = "editor error: " + err.Error()
+		return nil
+	}
+
+	// Validate the file path remains inside notes root (defense-in-depth).
+	rootPath, err := notesRoot()
+	if err != nil {
+		m.status = "resolve notes root failed: " + err.Error()
+		return nil
+	}
+	rel, err := filepath.Rel(rootPath, p)
+	if err != nil {
+		m.status = "path error: " + err.Error()
+		return nil
+	}
+	safePath, err := safeJoinWithin(rootPath, rel)
+	if err != nil {
+		m.status = "unsafe path: " + err.Error()
+		return nil
+	}
+
+	// Hand terminal control to the editor with TTY attached.
+	// tea.ExecProcess returns control to Bubble Tea and sends resumedMsg when done.
+	cmd := exec.Command(edPath, append(edArgs, editorFileArgs(edPath, safePath, line)...)...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	return tea.ExecProcess(cmd, func(error) tea.Msg { return resumedMsg{path: p} })
+}
+
+// targetDir returns the directory node new entries are created in: the
+// directory under the cursor, the parent of the note under the cursor, or the root.
+func (m *model) targetDir() *Node {
+	if len(m.visible) == 0 {
+		return m.root
+	}
+	cur := m.visible[m.cursor].N
+	if cur.Kind == kindSearch {
+		return m.root
+	}
+	if cur.IsDir {
+		return cur
+	}
+	if p := parentOf(m.root, cur); p != nil && p.Kind == kindEntry {
+		return p
+	}
+	if d := m.findDir(filepath.Dir(cur.Path)); d != nil {
+		return d // a note listed in a saved search: its real directory
+	}
+	return m.root
+}
+
+// displayPath renders p relative to the notes root for prompts and messages.
+func (m *model) displayPath(p string) string {
+	return rootedPath(m.root.Path, p)
+}
+
+// rootedPath returns p relative to the notes dir root, prefixed with the
+// root's name ("notes/projects/x.md"), as displayPath shows it. Subcommands
+// use it so their undo labels read like the TUI's.
+func rootedPath(root, p string) string {
+	name := filepath.Base(root)
+	rel, err := filepath.Rel(root, p)
+	if err != nil || rel == "." {
+		return name
+	}
+	return filepath.Join(name, rel)
+}
+
+// reload rebuilds the tree from disk while keeping the user's place:
+// expanded directories stay expanded and, when selectPath is set, the cursor
+// lands on that entry instead of the first row.
+func (m *model) reload(selectPath string) error {
+	rootPath, err := notesRoot()
+	if err != nil {
+		return err
+	}
+	root, err := buildTree(rootPath, m.query)
+	if err != nil {
+		return err
+	}
+	state := map[string]bool{}
+	dirState(m.root, state)
+	restoreDirState(root, state, m.query)
+
+	m.root = root
+	m.cursor = 0
+	m.recompute()
+	if selectPath != "" {
+		m.reveal(selectPath)
+	}
+	return nil
+}
+
+// startIndexRefresh refreshes the search index in the background, or queues
+// one more refresh when one is already running. Notes changed since the last
+// refresh are simply read until then (see noteIndex.lookup).
+func (m *model) startIndexRefresh() tea.Cmd {
+	if m.indexing {
+		m.indexAgain = true
+		return nil
+	}
+	m.indexing = true
+	return refreshIndexCmd(m.root.Path)
+}
+
+// reveal expands every ancestor of p and moves the cursor onto it.
+// Returns false when p is not part of the (possibly filtered) tree.
+func (m *model) reveal(p string) bool {
+	rel, err := filepath.Rel(m.root.Path, p)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return false
+	}
+	parts := strings.Split(rel, string(os.PathSeparator))
+	cur := m.root
+	for _, part := range parts[:len(parts)-1] {
+		next := childNamed(cur, part)
+		if next == nil || !next.IsDir {
+			return false
+		}
+		if err := expandIfNeeded(next, m.query); err != nil {
+			return false
+		}
+		cur = next
+	}
+
+	if m.archive != "" && inSubtree(p, m.archive) {
+		m.showArchive = true // the target lives in the archive: make it visible
+	}
+	m.recompute()
+	for i, v := range m.visible {
+		if v.N.Path == p {
+			m.cursor = i
+			m.adjustScroll()
+			return true
+		}
+	}
+	return false
+}
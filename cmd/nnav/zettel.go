@@ -0,0 +1,134 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// noteIDLayout is the time layout of Zettelkasten-style note IDs (YYYYMMDDhhmm).
+const noteIDLayout = "200601021504"
+
+// noteIDRE splits a file stem into a leading timestamp ID (12-14 digits, so
+// IDs with seconds are understood too) and the rest of the name.
+var noteIDRE = regexp.MustCompile(`^(\d{12,14})(?:[-_ ]+(.*))?$`)
+
+// idNaming reports whether new notes get ID file names ("naming=id" in ~/.nnav).
+// The default, "naming=plain", uses the typed name as-is.
+func idNaming() bool {
+	cfg, _ := loadConfig()
+	return strings.EqualFold(strings.TrimSpace(cfg["naming"]), "id")
+}
+
+// splitNoteID returns the ID prefix of a file stem and the remainder.
+// id is empty when the stem does not start with an ID.
+func splitNoteID(stem string) (id, rest string) {
+	m := noteIDRE.FindStringSubmatch(stem)
+	if m == nil {
+		return "", stem
+	}
+	return m[1], m[2]
+}
+
+// idFileName builds "<id>-<slug>.md" for a note titled title. The ID is the
+// current minute, moved forward until no existing note uses it, so IDs stay
+// unique across the notes tree.
+func idFileName(title string, now time.Time) (string, error) {
+	used, err := noteIDs()
+	if err != nil {
+		return "", err
+	}
+	t := now
+	for len(used[t.Format(noteIDLayout)]) > 0 {
+		t = t.Add(time.Minute)
+	}
+	name := t.Format(noteIDLayout)
+	if slug := slugify(title); slug != "" {
+		name += "-" + slug
+	}
+	return name + defaultNoteExt, nil
+}
+
+// noteIDs maps every note ID under the notes root to the notes carrying it.
+// Only file names are inspected, so this is cheap even for large trees; the
+// same directories as in the TUI are skipped (see hiddenDir).
+func noteIDs() (map[string][]string, error) {
+	root, err := notesRoot()
+	if err != nil {
+		return nil, err
+	}
+	tmplDir, _ := templatesDir()
+	ids := map[string][]string{}
+	err = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if d != nil && d.IsDir() && p != root {
+				return fs.SkipDir // unreadable directory: skip, like readDirNodes
+			}
+			return err
+		}
+		if d.IsDir() {
+			if p != root && hiddenDir(d.Name(), p, tmplDir) {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		name := d.Name()
+		if !allowedExts[strings.ToLower(filepath.Ext(name))] {
+			return nil
+		}
+		if id, _ := splitNoteID(strings.TrimSuffix(name, filepath.Ext(name))); id != "" {
+			ids[id] = append(ids[id], p)
+		}
+		return nil
+	})
+	return ids, err
+}
+
+// resolveNoteID returns the notes whose ID starts with query. Link syntax
+// such as "[[202610161432]]" and a trailing "-slug" are accepted.
+func resolveNoteID(query string) ([]string, error) {
+	q := strings.Trim(strings.TrimSpace(query), "[]")
+	if id, _ := splitNoteID(q); id != "" {
+		q = id
+	}
+	if q == "" || strings.Trim(q, "0123456789") != "" {
+		return nil, fmt.Errorf("not a note ID: %q", query)
+	}
+	ids, err := noteIDs()
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for id, paths := range ids {
+		if strings.HasPrefix(id, q) {
+			out = append(out, paths...)
+		}
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// cmdID implements `nnav id <query>`: print the path of every note whose ID
+// starts with query, one per line. Exits with an error when none match.
+func cmdID(args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: nnav id <id-or-prefix>")
+	}
+	paths, err := resolveNoteID(args[0])
+	if err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		return fmt.Errorf("no note with ID %s", args[0])
+	}
+	for _, p := range paths {
+		fmt.Fprintln(os.Stdout, p)
+	}
+	return nil
+}